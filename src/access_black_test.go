@@ -1,19 +1,23 @@
 package main_test
 
 import (
+	"fmt"
+	"runtime"
 	"testing"
+	"time"
 
 	wecs "github.com/averagestardust/wecs"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestAccessQuery(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 	Vector := wecs.NewComponent[struct {
 		x float32
 		y float32
-	}]()
+	}](registry, "vector")
 
 	world.New(Integer)
 	vectorEntity := world.New(Vector)
@@ -25,8 +29,9 @@ func TestAccessQuery(t *testing.T) {
 }
 
 func TestAccessAlive(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 
 	fake := wecs.Entity(2304)
 	assert.False(t, world.Alive(fake))
@@ -42,8 +47,9 @@ func TestAccessAlive(t *testing.T) {
 }
 
 func TestAccessExists(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 
 	fake := wecs.Entity(2304)
 	assert.False(t, world.Exists(fake))
@@ -59,8 +65,9 @@ func TestAccessExists(t *testing.T) {
 }
 
 func TestAccessEmptyQueueDelete(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 
 	entity := world.New(Integer)
 	assert.True(t, world.Alive(entity))
@@ -76,8 +83,9 @@ func TestAccessEmptyQueueDelete(t *testing.T) {
 }
 
 func TestAccessDelete(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 
 	entity := world.New(Integer)
 	assert.True(t, world.Alive(entity))
@@ -93,8 +101,9 @@ func TestAccessDelete(t *testing.T) {
 }
 
 func TestAccessDeleteImmediately(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 
 	entity := world.New(Integer)
 	assert.True(t, world.Alive(entity))
@@ -106,12 +115,13 @@ func TestAccessDeleteImmediately(t *testing.T) {
 }
 
 func TestAccessNew(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 	Vector := wecs.NewComponent[struct {
 		x float32
 		y float32
-	}]()
+	}](registry, "vector")
 
 	entityA := world.New(Integer)
 	entityB := world.New(Integer)
@@ -139,12 +149,13 @@ func TestAccessNew(t *testing.T) {
 }
 
 func TestAccessNewBatch(t *testing.T) {
-	world := wecs.NewWorld()
-	Integer := wecs.NewComponent[uint32]()
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
 	Vector := wecs.NewComponent[struct {
 		x float32
 		y float32
-	}]()
+	}](registry, "vector")
 
 	count := 0
 	for entity := range world.NewBatch(25, Integer) {
@@ -155,3 +166,1163 @@ func TestAccessNewBatch(t *testing.T) {
 
 	assert.Equal(t, 25, count)
 }
+
+func TestAccessStaleEntity(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+
+	stale := world.New(Integer)
+	world.Delete(stale)
+
+	// the deleted slot is recycled, but the old handle must not see the new entity
+	entity := world.New(Integer)
+	assert.NotEqual(t, stale, entity)
+	assert.True(t, world.Alive(entity))
+	assert.False(t, world.Alive(stale))
+	assert.False(t, world.Exists(stale))
+	assert.False(t, Integer.Has(world, stale))
+}
+
+func TestAccessRegistry(t *testing.T) {
+	registryA := wecs.NewRegistry()
+	registryB := wecs.NewRegistry()
+	worldA := wecs.NewWorld(registryA)
+	worldB := wecs.NewWorld(registryB)
+
+	IntegerA := wecs.NewComponent[uint32](registryA, "integer")
+	FlagA := wecs.NewTag(registryA, "flag")
+	IntegerB := wecs.NewComponent[uint32](registryB, "integer")
+	FlagB := wecs.NewTag(registryB, "flag")
+
+	// isolated registries assign the same ids in the same order
+	assert.Equal(t, IntegerA.PartId(), IntegerB.PartId())
+	assert.Equal(t, FlagA.PartId(), FlagB.PartId())
+
+	entityA := worldA.New(IntegerA, FlagA)
+	entityB := worldB.New(IntegerB)
+
+	assert.True(t, FlagA.Has(worldA, entityA))
+	assert.False(t, FlagB.Has(worldB, entityB))
+	assert.Same(t, registryA, worldA.Registry())
+}
+
+func TestAccessPointers(t *testing.T) {
+	type named struct {
+		Name  string
+		Tags  []string
+		Score map[string]int
+	}
+
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Named := wecs.NewComponent[named](registry, "named")
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+
+	entities := []wecs.Entity{}
+	for i := range 100 {
+		entity := world.New(Named)
+		*Named.Get(world, entity) = named{
+			Name:  fmt.Sprint("entity ", i),
+			Tags:  []string{fmt.Sprint(i)},
+			Score: map[string]int{"score": i},
+		}
+		entities = append(entities, entity)
+	}
+
+	// move half the entities and delete a quarter, shuffling values between rows and pages
+	for i, entity := range entities {
+		if i%2 == 0 {
+			Integer.Add(world, entity)
+		}
+		if i%4 == 1 {
+			world.Delete(entity)
+		}
+	}
+
+	runtime.GC()
+
+	for i, entity := range entities {
+		if i%4 == 1 {
+			continue
+		}
+
+		assert.Equal(t, named{
+			Name:  fmt.Sprint("entity ", i),
+			Tags:  []string{fmt.Sprint(i)},
+			Score: map[string]int{"score": i},
+		}, *Named.Get(world, entity))
+	}
+}
+
+func TestAccessComponentQueryWrite(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+	Flag := wecs.NewTag(registry, "flag")
+
+	// spread entities over several pages
+	entities := []wecs.Entity{}
+	for i := range 12 {
+		switch i % 3 {
+		case 0:
+			entities = append(entities, world.New(Integer))
+		case 1:
+			entities = append(entities, world.New(Integer, Flag))
+		case 2:
+			entities = append(entities, world.New(Integer, Name))
+		}
+	}
+
+	for integer := range Integer.Query(world, wecs.NewFilter()) {
+		*integer += 5
+	}
+	for name := range Name.Query(world, wecs.NewFilter()) {
+		*name = "named"
+	}
+
+	for i, entity := range entities {
+		assert.Equal(t, uint32(5), *Integer.Get(world, entity))
+
+		if i%3 == 2 {
+			assert.Equal(t, "named", *Name.Get(world, entity))
+		}
+	}
+}
+
+func TestAccessPairQueryWrite(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Vector := wecs.NewComponent[struct {
+		x float32
+		y float32
+	}](registry, "vector")
+	Flag := wecs.NewTag(registry, "flag")
+
+	integerOnly := world.New(Integer)
+	entities := []wecs.Entity{
+		world.New(Integer, Vector),
+		world.New(Integer, Vector, Flag),
+		world.New(Integer, Vector),
+	}
+
+	count := 0
+	for integer, vector := range wecs.NewPair(Integer, Vector).Query(world, wecs.NewFilter()) {
+		*integer = 3
+		vector.x = 1.5
+		count++
+	}
+
+	// entities without both components are skipped
+	assert.Equal(t, 3, count)
+	assert.Equal(t, uint32(0), *Integer.Get(world, integerOnly))
+
+	for _, entity := range entities {
+		assert.Equal(t, uint32(3), *Integer.Get(world, entity))
+		assert.Equal(t, float32(1.5), Vector.Get(world, entity).x)
+	}
+}
+
+func TestAccessNewWith(t *testing.T) {
+	type vector struct {
+		X float32
+		Y float32
+	}
+
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Position := wecs.NewComponent[vector](registry, "position")
+	Velocity := wecs.NewComponent[vector](registry, "velocity")
+	Name := wecs.NewComponent[string](registry, "name")
+	Enemy := wecs.NewTag(registry, "enemy")
+
+	entity := world.New(Position.With(vector{1, 2}), Velocity.With(vector{0, -1}), Name.With("goblin"), Enemy)
+
+	assert.Equal(t, vector{1, 2}, *Position.Get(world, entity))
+	assert.Equal(t, vector{0, -1}, *Velocity.Get(world, entity))
+	assert.Equal(t, "goblin", *Name.Get(world, entity))
+	assert.True(t, Enemy.Has(world, entity))
+
+	// plain components and values mix, in the same archetype as without values
+	other := world.New(Position, Velocity.With(vector{5, 5}), Name, Enemy)
+	assert.Equal(t, vector{}, *Position.Get(world, other))
+	assert.Equal(t, vector{5, 5}, *Velocity.Get(world, other))
+
+	count := 0
+	for range world.Query(wecs.NewFilter().Exactly(Position, Velocity, Name, Enemy)) {
+		count++
+	}
+	assert.Equal(t, 2, count)
+
+	// existing entities are untouched by later batches
+	for batchEntity := range world.NewBatch(10, Position.With(vector{3, 3}), Velocity, Name, Enemy) {
+		assert.Equal(t, vector{3, 3}, *Position.Get(world, batchEntity))
+		assert.Equal(t, vector{}, *Velocity.Get(world, batchEntity))
+	}
+	assert.Equal(t, vector{1, 2}, *Position.Get(world, entity))
+}
+
+func TestAccessSet(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+
+	entity := world.New(Integer)
+
+	// add when missing
+	assert.True(t, Name.Set(world, entity, "first"))
+	assert.Equal(t, "first", *Name.Get(world, entity))
+
+	// write in place when present
+	assert.False(t, Name.Set(world, entity, "second"))
+	assert.Equal(t, "second", *Name.Get(world, entity))
+
+	assert.False(t, Integer.Set(world, entity, 9))
+	assert.Equal(t, uint32(9), *Integer.Get(world, entity))
+
+	// deleted entities are left alone
+	world.Delete(entity)
+	assert.False(t, Integer.Set(world, entity, 4))
+	assert.False(t, world.Exists(entity))
+}
+
+func TestAccessMutate(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+	Flag := wecs.NewTag(registry, "flag")
+	Other := wecs.NewTag(registry, "other")
+
+	entity := world.New(Integer.With(4), Flag)
+
+	assert.True(t, world.Mutate(entity).Add(Name.With("mutated"), Other).Remove(Flag).Apply())
+	assert.Equal(t, uint32(4), *Integer.Get(world, entity))
+	assert.Equal(t, "mutated", *Name.Get(world, entity))
+	assert.True(t, Other.Has(world, entity))
+	assert.False(t, Flag.Has(world, entity))
+
+	// removals take priority over additions
+	assert.True(t, world.Mutate(entity).Add(Flag, Integer.With(8)).Remove(Integer).Apply())
+	assert.True(t, Flag.Has(world, entity))
+	assert.False(t, Integer.Has(world, entity))
+
+	world.Delete(entity)
+	assert.False(t, world.Mutate(entity).Add(Integer).Apply())
+}
+
+func TestAccessMutateQuery(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+	Flag := wecs.NewTag(registry, "flag")
+
+	flagged := []wecs.Entity{}
+	for i := range 10 {
+		flagged = append(flagged, world.New(Integer.With(uint32(i)), Flag))
+	}
+	named := world.New(Integer.With(20), Name.With("named"), Flag)
+	unflagged := world.New(Integer.With(30))
+
+	count := world.MutateQuery(wecs.NewFilter().IncludeExact(Flag)).
+		Add(Name.With("bulk")).
+		Remove(Flag).
+		Apply()
+	assert.Equal(t, 11, count)
+
+	for i, entity := range flagged {
+		assert.Equal(t, uint32(i), *Integer.Get(world, entity))
+		assert.Equal(t, "bulk", *Name.Get(world, entity))
+		assert.False(t, Flag.Has(world, entity))
+	}
+
+	assert.Equal(t, uint32(20), *Integer.Get(world, named))
+	assert.Equal(t, "bulk", *Name.Get(world, named))
+	assert.False(t, Name.Has(world, unflagged))
+
+	// moved entities are still found in their new archetype
+	total := 0
+	for range world.Query(wecs.NewFilter().Exactly(Integer, Name)) {
+		total++
+	}
+	assert.Equal(t, 11, total)
+}
+
+func TestAccessCommands(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+	Flag := wecs.NewTag(registry, "flag")
+
+	entities := []wecs.Entity{}
+	for i := range 10 {
+		entities = append(entities, world.New(Integer.With(uint32(i))))
+	}
+
+	// structural changes during a query are held back until the flush
+	commands := wecs.NewCommands()
+	for entity := range world.Query(wecs.NewFilter().IncludeExact(Integer)) {
+		value := *Integer.Get(world, entity)
+		switch {
+		case value%3 == 0:
+			commands.Delete(entity)
+		case value%3 == 1:
+			commands.Add(entity, Flag)
+			commands.Set(entity, Name.With("one"))
+		default:
+			commands.Remove(entity, Integer)
+			commands.New(Integer.With(value+100), Flag)
+		}
+	}
+	assert.Equal(t, 16, commands.Len())
+	assert.True(t, commands.Deleted(entities[0]))
+	assert.True(t, world.Exists(entities[0]))
+
+	commands.Flush(world)
+	assert.Equal(t, 0, commands.Len())
+	assert.False(t, commands.Deleted(entities[0]))
+
+	for i, entity := range entities {
+		switch i % 3 {
+		case 0:
+			assert.False(t, world.Exists(entity))
+		case 1:
+			assert.True(t, Flag.Has(world, entity))
+			assert.Equal(t, "one", *Name.Get(world, entity))
+		default:
+			assert.False(t, Integer.Has(world, entity))
+		}
+	}
+
+	spawned := []uint32{}
+	for value := range Integer.Query(world, wecs.NewFilter().Exactly(Integer, Flag)) {
+		spawned = append(spawned, *value)
+	}
+	assert.ElementsMatch(t, []uint32{102, 105, 108}, spawned)
+}
+
+func TestAccessCommandsOrder(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Flag := wecs.NewTag(registry, "flag")
+
+	entity := world.New(Integer)
+
+	// later commands see the results of earlier ones
+	commands := world.Commands()
+	commands.Set(entity, Integer.With(1))
+	commands.Add(entity, Flag)
+	commands.Set(entity, Integer.With(2))
+	commands.Remove(entity, Flag)
+	commands.Delete(entity)
+	commands.Set(entity, Integer.With(3))
+	assert.False(t, world.Alive(entity))
+
+	world.EmptyDeleteQueue()
+	assert.False(t, world.Exists(entity))
+	assert.Equal(t, 0, commands.Len())
+
+	entity = world.New(Integer)
+	commands.Add(entity, Flag)
+	commands.Set(entity, Integer.With(4))
+	commands.Remove(entity, Flag)
+	world.EmptyDeleteQueue()
+	assert.Equal(t, uint32(4), *Integer.Get(world, entity))
+	assert.False(t, Flag.Has(world, entity))
+
+	// systems queue commands in their own buffer, applied once they finish
+	system := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		world.Commands().Set(entity, Integer.With(5))
+		world.QueueDelete(entity)
+		assert.False(t, world.Alive(entity))
+	})
+	system.Run(world, time.Second)
+
+	assert.Equal(t, 0, commands.Len())
+	assert.False(t, world.Exists(entity))
+}
+
+func TestAccessQueryOrder(t *testing.T) {
+	var expected []wecs.Entity
+
+	for range 50 {
+		registry := wecs.NewRegistry()
+		world := wecs.NewWorld(registry)
+		Integer := wecs.NewComponent[uint32](registry, "integer")
+		tags := []wecs.Tag{}
+		for i := range 8 {
+			tags = append(tags, wecs.NewTag(registry, fmt.Sprint("tag", i)))
+		}
+
+		// spread entities over many archetypes, so any randomness in page order would show
+		for i := range 64 {
+			entity := world.New(Integer.With(uint32(i)))
+			for bit, tag := range tags {
+				if i&(1<<bit) != 0 {
+					tag.Add(world, entity)
+				}
+			}
+		}
+
+		order := []wecs.Entity{}
+		for entity := range world.Query(wecs.NewFilter().IncludeExact(Integer)) {
+			order = append(order, entity)
+		}
+		assert.Len(t, order, 64)
+
+		values := []uint32{}
+		for value := range Integer.Query(world, wecs.NewFilter()) {
+			values = append(values, *value)
+		}
+		for i, entity := range order {
+			assert.Equal(t, *Integer.Get(world, entity), values[i])
+		}
+
+		if expected == nil {
+			expected = order
+		}
+		assert.Equal(t, expected, order)
+	}
+}
+
+func TestAccessCachedQuery(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+	Flag := wecs.NewTag(registry, "flag")
+
+	filter := wecs.NewFilter().IncludeExact(Integer).ExcludeAny(Flag)
+	query := wecs.NewCachedQuery(filter)
+
+	count := func(matcher wecs.Matcher) (n int) {
+		for range world.Query(matcher) {
+			n++
+		}
+		return n
+	}
+
+	world.New(Integer)
+	world.New(Integer, Flag)
+	assert.Equal(t, 1, count(query))
+
+	// archetypes created after the first query are still found
+	world.New(Integer, Name)
+	world.New(Name)
+	entity := world.New(Integer, Name, Flag)
+	assert.Equal(t, 2, count(query))
+	assert.Equal(t, count(filter), count(query))
+
+	Flag.Delete(world, entity)
+	assert.Equal(t, 3, count(query))
+
+	total := uint32(0)
+	for integer := range Integer.Query(world, query) {
+		*integer = 4
+		total += *integer
+	}
+	assert.Equal(t, uint32(12), total)
+
+	assert.Equal(t, 3, world.MutateQuery(query).Add(Flag).Apply())
+	assert.Equal(t, 0, count(query))
+
+	// using the query on another world starts over
+	other := wecs.NewWorld(registry)
+	other.New(Integer)
+	n := 0
+	for range other.Query(query) {
+		n++
+	}
+	assert.Equal(t, 1, n)
+}
+
+func TestAccessTupleQuery(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Short := wecs.NewComponent[uint16](registry, "short")
+	Name := wecs.NewComponent[string](registry, "name")
+
+	entities := []wecs.Entity{}
+	for i := range 5 {
+		entities = append(entities, world.New(Integer.With(uint32(i)), Short, Name))
+	}
+	world.New(Integer, Short)
+
+	query := wecs.NewQuery3(Integer, Short, Name)
+	for entity, row := range query.QueryWithEntity(world, wecs.NewFilter()) {
+		*row.B = uint16(*row.A) * 2
+		*row.C = fmt.Sprint(entity)
+	}
+
+	count := 0
+	for row := range query.Query(world, wecs.NewFilter()) {
+		assert.Equal(t, uint16(*row.A)*2, *row.B)
+		count++
+	}
+	assert.Equal(t, 5, count)
+
+	for _, entity := range entities {
+		assert.Equal(t, fmt.Sprint(entity), *Name.Get(world, entity))
+	}
+}
+
+func TestAccessTupleQueryEight(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	A := wecs.NewComponent[uint8](registry, "a")
+	B := wecs.NewComponent[uint16](registry, "b")
+	C := wecs.NewComponent[uint32](registry, "c")
+	D := wecs.NewComponent[uint64](registry, "d")
+	E := wecs.NewComponent[int8](registry, "e")
+	F := wecs.NewComponent[int16](registry, "f")
+	G := wecs.NewComponent[int32](registry, "g")
+	H := wecs.NewComponent[string](registry, "h")
+
+	entity := world.New(A.With(1), B.With(2), C.With(3), D.With(4), E.With(5), F.With(6), G.With(7), H.With("8"))
+	world.New(A, B, C, D, E, F, G)
+
+	count := 0
+	for found, row := range wecs.NewQuery8(A, B, C, D, E, F, G, H).QueryWithEntity(world, wecs.NewFilter()) {
+		assert.Equal(t, entity, found)
+		assert.Equal(t, wecs.Row8[uint8, uint16, uint32, uint64, int8, int16, int32, string]{
+			A: A.Get(world, entity), B: B.Get(world, entity), C: C.Get(world, entity), D: D.Get(world, entity),
+			E: E.Get(world, entity), F: F.Get(world, entity), G: G.Get(world, entity), H: H.Get(world, entity),
+		}, row)
+		*row.H = "eight"
+		count++
+	}
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "eight", *H.Get(world, entity))
+}
+
+func TestAccessQueryWithEntity(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+
+	values := map[wecs.Entity]uint32{}
+	for i := range 10 {
+		entity := world.New(Integer.With(uint32(i)))
+		if i%2 == 0 {
+			Name.Add(world, entity)
+		}
+		values[entity] = uint32(i)
+	}
+
+	count := 0
+	for entity, integer := range Integer.QueryWithEntity(world, wecs.NewFilter()) {
+		assert.Equal(t, values[entity], *integer)
+		*integer += 100
+		count++
+	}
+	assert.Equal(t, 10, count)
+
+	count = 0
+	for entity, row := range wecs.NewPair(Integer, Name).QueryWithEntity(world, wecs.NewFilter()) {
+		assert.Equal(t, values[entity]+100, *row.A)
+		*row.B = fmt.Sprint(values[entity])
+		count++
+	}
+	assert.Equal(t, 5, count)
+
+	for entity, value := range values {
+		assert.Equal(t, value+100, *Integer.Get(world, entity))
+		if value%2 == 0 {
+			assert.Equal(t, fmt.Sprint(value), *Name.Get(world, entity))
+		}
+	}
+}
+
+func TestAccessOptional(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+	Short := wecs.NewComponent[uint16](registry, "short")
+
+	plain := world.New(Integer.With(1))
+	named := world.New(Integer.With(2), Name.With("two"))
+	world.New(Name.With("nameless"))
+
+	found := map[wecs.Entity]*string{}
+	for entity, row := range wecs.NewPair(Integer, wecs.Optional(Name)).QueryWithEntity(world, wecs.NewFilter()) {
+		assert.NotNil(t, row.A)
+		found[entity] = row.B
+	}
+	assert.Len(t, found, 2)
+	assert.Nil(t, found[plain])
+	assert.Equal(t, "two", *found[named])
+
+	// optional data still points into the world
+	for _, name := range wecs.NewPair(Integer, wecs.Optional(Name)).Query(world, wecs.NewFilter()) {
+		if name != nil {
+			*name = "changed"
+		}
+	}
+	assert.Equal(t, "changed", *Name.Get(world, named))
+
+	count := 0
+	for row := range wecs.NewQuery3(Integer, wecs.Optional(Name), wecs.Optional(Short)).Query(world, wecs.NewFilter()) {
+		assert.Nil(t, row.C)
+		count++
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestAccessFilterComposition(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Player := wecs.NewTag(registry, "player")
+	Alive := wecs.NewTag(registry, "alive")
+	NPC := wecs.NewTag(registry, "npc")
+	Hostile := wecs.NewTag(registry, "hostile")
+
+	alivePlayer := world.New(Player, Alive)
+	world.New(Player)
+	hostileNPC := world.New(NPC, Hostile)
+	world.New(NPC, Alive)
+	both := world.New(Player, Alive, NPC, Hostile)
+
+	query := func(filter wecs.Filter) []wecs.Entity {
+		entities := []wecs.Entity{}
+		for entity := range world.Query(filter) {
+			entities = append(entities, entity)
+		}
+		return entities
+	}
+
+	// (Player AND Alive) OR (NPC AND Hostile), without duplicates
+	filter := wecs.Or(
+		wecs.NewFilter().IncludeExact(Player, Alive),
+		wecs.NewFilter().IncludeExact(NPC, Hostile))
+	assert.ElementsMatch(t, []wecs.Entity{alivePlayer, hostileNPC, both}, query(filter))
+
+	assert.ElementsMatch(t, []wecs.Entity{alivePlayer, hostileNPC}, query(wecs.And(filter, wecs.Not(
+		wecs.NewFilter().IncludeExact(Player, NPC)))))
+
+	// filters nest and combine with other layers
+	nested := wecs.Or(
+		wecs.Not(wecs.NewFilter().IncludeAny(Player, NPC)),
+		wecs.And(wecs.NewFilter().IncludeExact(NPC), wecs.Not(wecs.NewFilter().IncludeExact(Hostile))),
+	).ExcludeAny(Player)
+	assert.Len(t, query(nested), 1)
+
+	assert.Empty(t, query(wecs.Or()))
+	assert.Len(t, query(wecs.And()), 5)
+}
+
+func TestAccessChangeDetection(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+
+	first := world.New(Integer)
+	second := world.New(Integer.With(2))
+
+	var added, changed []wecs.Entity
+	watch := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		added, changed = nil, nil
+		for entity := range world.Query(wecs.NewFilter().Added(Integer)) {
+			added = append(added, entity)
+		}
+		for entity := range world.Query(wecs.NewFilter().Changed(Integer)) {
+			changed = append(changed, entity)
+		}
+	})
+
+	// everything is new the first time a system runs
+	watch.Run(world, time.Second)
+	assert.ElementsMatch(t, []wecs.Entity{first, second}, added)
+	assert.ElementsMatch(t, []wecs.Entity{first, second}, changed)
+
+	watch.Run(world, time.Second)
+	assert.Empty(t, added)
+	assert.Empty(t, changed)
+
+	// writing through Get isn't tracked, but Set and Mut are
+	*Integer.Get(world, first) = 10
+	Integer.Set(world, second, 20)
+	watch.Run(world, time.Second)
+	assert.Empty(t, added)
+	assert.Equal(t, []wecs.Entity{second}, changed)
+
+	*Integer.Mut(world, first) = 11
+	third := world.New(Name)
+	Integer.Add(world, third)
+	// moving an entity keeps the ticks of components it already had
+	Name.Add(world, second)
+	watch.Run(world, time.Second)
+	assert.Equal(t, []wecs.Entity{third}, added)
+	assert.ElementsMatch(t, []wecs.Entity{first, third}, changed)
+
+	for integer := range Integer.QueryMut(world, wecs.NewFilter().ExcludeAny(Name)) {
+		*integer++
+	}
+	watch.Run(world, time.Second)
+	assert.Empty(t, added)
+	assert.Equal(t, []wecs.Entity{first}, changed)
+}
+
+func TestAccessChangeDetectionTags(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Flag := wecs.NewTag(registry, "flag")
+
+	first := world.New(Flag, Integer)
+
+	var added, changed []wecs.Entity
+	watch := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		added, changed = nil, nil
+		for entity := range world.Query(wecs.NewFilter().Added(Flag)) {
+			added = append(added, entity)
+		}
+		for entity := range world.Query(wecs.NewFilter().Changed(Flag)) {
+			changed = append(changed, entity)
+		}
+	})
+
+	watch.Run(world, time.Second)
+	assert.Equal(t, []wecs.Entity{first}, added)
+	assert.Equal(t, []wecs.Entity{first}, changed)
+
+	// changing other parts of an entity keeps the tick of it's tags
+	second := world.New(Integer)
+	Integer.Set(world, first, 1)
+	watch.Run(world, time.Second)
+	assert.Empty(t, added)
+	assert.Empty(t, changed)
+
+	Flag.Add(world, second)
+	watch.Run(world, time.Second)
+	assert.Equal(t, []wecs.Entity{second}, added)
+	assert.Equal(t, []wecs.Entity{second}, changed)
+}
+
+func TestAccessChangeDetectionQueries(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Float := wecs.NewComponent[float32](registry, "float")
+	Name := wecs.NewComponent[string](registry, "name")
+
+	first := world.New(Integer, Float)
+	second := world.New(Integer, Float, Name)
+
+	var integers, floats, names []wecs.Entity
+	watch := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		integers, floats, names = nil, nil, nil
+		for entity := range world.Query(wecs.NewFilter().Changed(Integer)) {
+			integers = append(integers, entity)
+		}
+		for entity := range world.Query(wecs.NewFilter().Changed(Float)) {
+			floats = append(floats, entity)
+		}
+		for entity := range world.Query(wecs.NewFilter().Changed(Name)) {
+			names = append(names, entity)
+		}
+	})
+	watch.Run(world, time.Second)
+
+	// writing through the plain queries isn't tracked
+	pair := wecs.NewPair(Integer, Float)
+	for integer, float := range pair.Query(world, wecs.NewFilter()) {
+		*integer, *float = 1, 1
+	}
+	for row := range wecs.NewQuery3(Integer, Float, Name).Query(world, wecs.NewFilter()) {
+		*row.C = "name"
+	}
+	watch.Run(world, time.Second)
+	assert.Empty(t, integers)
+	assert.Empty(t, floats)
+	assert.Empty(t, names)
+
+	// a system writing through a pair marks every component of the pair
+	writer := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		for integer, float := range pair.QueryMut(world, wecs.NewFilter().ExcludeAny(Name)) {
+			*integer, *float = 2, 2
+		}
+	})
+	writer.Run(world, time.Second)
+	watch.Run(world, time.Second)
+	assert.Equal(t, []wecs.Entity{first}, integers)
+	assert.Equal(t, []wecs.Entity{first}, floats)
+	assert.Empty(t, names)
+
+	// optional components are only marked on entities that have them
+	for row := range wecs.NewQuery3(Integer, Float, wecs.Optional(Name)).QueryMut(world, wecs.NewFilter()) {
+		if row.C != nil {
+			*row.C = "changed"
+		}
+	}
+	watch.Run(world, time.Second)
+	assert.ElementsMatch(t, []wecs.Entity{first, second}, integers)
+	assert.ElementsMatch(t, []wecs.Entity{first, second}, floats)
+	assert.Equal(t, []wecs.Entity{second}, names)
+	assert.Equal(t, "changed", *Name.Get(world, second))
+}
+
+func TestAccessChangeDetectionSystems(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Flag := wecs.NewTag(registry, "flag")
+
+	entity := world.New(Integer)
+
+	// a system doesn't see it's own changes, but later systems do
+	writerSeen, readerSeen := 0, 0
+	writer := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		for range world.Query(wecs.NewFilter().Changed(Integer)) {
+			writerSeen++
+		}
+		Integer.Set(world, entity, 1)
+	})
+	reader := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		for range world.Query(wecs.NewFilter().Changed(Integer)) {
+			readerSeen++
+		}
+	})
+
+	scheduler := wecs.NewScheduler().AddStage(writer).AddStage(reader)
+	for range 3 {
+		scheduler.Run(world, time.Second)
+	}
+	assert.Equal(t, 1, writerSeen)
+	assert.Equal(t, 3, readerSeen)
+
+	// row checks work with composed filters, cached queries and query mutations
+	other := world.New(Integer)
+	query := wecs.NewCachedQuery(wecs.Or(
+		wecs.NewFilter().Changed(Integer),
+		wecs.Not(wecs.NewFilter().IncludeExact(Integer))))
+	var moved int
+	mutate := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		moved = world.MutateQuery(query).Add(Flag).Apply()
+	})
+
+	mutate.Run(world, time.Second)
+	assert.Equal(t, 2, moved)
+
+	Integer.Set(world, other, 3)
+	world.New(Flag)
+	mutate.Run(world, time.Second)
+	assert.Equal(t, 2, moved)
+	assert.True(t, Flag.Has(world, other))
+}
+
+func TestAccessRemoved(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Handle := wecs.NewComponent[uint32](registry, "handle")
+	Name := wecs.NewComponent[string](registry, "name")
+	Flag := wecs.NewTag(registry, "flag")
+	Handle.RecordRemoved(registry)
+	Name.RecordRemoved(registry)
+	Flag.RecordRemoved(registry)
+	registry.RecordDeleted()
+
+	type seen struct {
+		handles map[wecs.Entity]uint32
+		names   map[wecs.Entity]string
+		flags   []wecs.Entity
+		deleted []wecs.Entity
+	}
+	cleanup := wecs.NewSystem(seen{}, func(world *wecs.World, state *seen, delta time.Duration, runtime time.Duration) {
+		*state = seen{handles: map[wecs.Entity]uint32{}, names: map[wecs.Entity]string{}}
+		for entity, handle := range Handle.Removed(world) {
+			state.handles[entity] = *handle
+		}
+		for entity, name := range Name.Removed(world) {
+			state.names[entity] = *name
+		}
+		for entity := range Flag.Removed(world) {
+			state.flags = append(state.flags, entity)
+		}
+		for entity := range world.Deleted() {
+			state.deleted = append(state.deleted, entity)
+		}
+	})
+	other := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {})
+	scheduler := wecs.NewScheduler().AddStage(cleanup, other)
+	state := cleanup.State().(*seen)
+
+	first := world.New(Handle.With(1), Name.With("first"), Flag)
+	second := world.New(Handle.With(2), Name.With("second"))
+	Handle.Delete(world, first)
+	world.Delete(second)
+	Flag.Delete(world, first)
+
+	scheduler.Run(world, time.Second)
+	assert.Equal(t, map[wecs.Entity]uint32{first: 1, second: 2}, state.handles)
+	assert.Equal(t, map[wecs.Entity]string{second: "second"}, state.names)
+	assert.Equal(t, []wecs.Entity{first}, state.flags)
+	assert.Equal(t, []wecs.Entity{second}, state.deleted)
+
+	// every system has seen the removals, so they aren't seen again
+	scheduler.Run(world, time.Second)
+	assert.Empty(t, state.handles)
+	assert.Empty(t, state.names)
+	assert.Empty(t, state.flags)
+	assert.Empty(t, state.deleted)
+
+	// removals made by commands are seen on the next run
+	world.Commands().Delete(first)
+	world.EmptyDeleteQueue()
+	cleanup.Run(world, time.Second)
+	assert.Equal(t, map[wecs.Entity]string{first: "first"}, state.names)
+	assert.Equal(t, []wecs.Entity{first}, state.deleted)
+
+	world.ClearRemoved()
+	count := 0
+	for range Name.Removed(world) {
+		count++
+	}
+	assert.Equal(t, 0, count)
+}
+
+func TestAccessRemovedFirstRun(t *testing.T) {
+	registry := wecs.NewRegistry()
+	Handle := wecs.NewComponent[uint32](registry, "handle")
+	Handle.RecordRemoved(registry)
+	registry.RecordDeleted()
+	world := wecs.NewWorld(registry)
+
+	first := world.New(Handle.With(1))
+	second := world.New(Handle.With(2))
+
+	// removals made in the first stage of the first run are seen by a later stage, which hasn't looked before
+	remove := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		Handle.Delete(world, first)
+		world.Commands().Delete(second)
+	})
+	handles := map[wecs.Entity]uint32{}
+	var deleted []wecs.Entity
+	cleanup := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		for entity, handle := range Handle.Removed(world) {
+			handles[entity] = *handle
+		}
+		for entity := range world.Deleted() {
+			deleted = append(deleted, entity)
+		}
+	})
+
+	wecs.NewScheduler().AddStage(remove).AddStage(cleanup).Run(world, time.Second)
+	assert.Equal(t, map[wecs.Entity]uint32{first: 1, second: 2}, handles)
+	assert.Equal(t, []wecs.Entity{second}, deleted)
+}
+
+func TestAccessHooks(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Body := wecs.NewComponent[uint32](registry, "body")
+	Name := wecs.NewComponent[string](registry, "name")
+	Flag := wecs.NewTag(registry, "flag")
+
+	events := []string{}
+	Body.OnAdd(world, func(world *wecs.World, entity wecs.Entity, data *uint32) {
+		events = append(events, fmt.Sprint("add ", *data))
+	})
+	Body.OnRemove(world, func(world *wecs.World, entity wecs.Entity, data *uint32) {
+		events = append(events, fmt.Sprint("remove ", *data))
+	})
+	Body.OnSet(world, func(world *wecs.World, entity wecs.Entity, data *uint32) {
+		events = append(events, fmt.Sprint("set ", *data))
+	})
+
+	// add hooks see the initial data
+	entity := world.New(Body.With(1), Flag)
+	assert.Equal(t, []string{"add 1", "set 1"}, events)
+
+	events = nil
+	world.New(Name)
+	Name.Set(world, entity, "named")
+	Flag.Delete(world, entity)
+	assert.Empty(t, events)
+
+	Body.Set(world, entity, 2)
+	*Body.Mut(world, entity) = 3
+	assert.True(t, Body.Delete(world, entity))
+	assert.False(t, Body.Delete(world, entity))
+	assert.True(t, Body.Add(world, entity))
+	assert.False(t, Body.Set(world, entity, 4))
+	assert.Equal(t, []string{"set 2", "remove 3", "add 0", "set 4"}, events)
+
+	events = nil
+	world.Mutate(entity).Remove(Body).Apply()
+	world.Mutate(entity).Add(Body.With(5)).Apply()
+	world.Delete(entity)
+	assert.Equal(t, []string{"remove 4", "add 5", "set 5", "remove 5"}, events)
+
+	events = nil
+	for i := range 3 {
+		world.New(Body.With(uint32(i)), Flag)
+	}
+	world.MutateQuery(wecs.NewFilter().IncludeExact(Flag)).Remove(Body).Apply()
+	assert.Equal(t, []string{"add 0", "set 0", "add 1", "set 1", "add 2", "set 2", "remove 0", "remove 1", "remove 2"}, events)
+}
+
+func TestAccessHooksQueryMutation(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	A := wecs.NewComponent[uint32](registry, "a")
+	B := wecs.NewComponent[uint32](registry, "b")
+
+	sets := map[wecs.Entity]int{}
+	B.OnSet(world, func(world *wecs.World, entity wecs.Entity, data *uint32) {
+		sets[entity]++
+	})
+
+	// the page of y moves into the page of x, which is also matched
+	y := world.New(A)
+	x := world.New(A, B.With(1))
+	clear(sets)
+
+	count := world.MutateQuery(wecs.NewFilter().IncludeExact(A)).Add(B.With(5)).Apply()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, map[wecs.Entity]int{x: 1, y: 1}, sets)
+	assert.Equal(t, uint32(5), *B.Get(world, x))
+	assert.Equal(t, uint32(5), *B.Get(world, y))
+
+	// moved entities are only marked as changed once, by the mutation
+	var changed []wecs.Entity
+	z := world.New(A)
+	mutate := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		world.MutateQuery(wecs.NewFilter().IncludeExact(A)).Add(B.With(6)).Apply()
+	})
+	watch := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		changed = nil
+		for entity := range world.Query(wecs.NewFilter().Changed(B)) {
+			changed = append(changed, entity)
+		}
+	})
+	watch.Run(world, time.Second)
+	clear(sets)
+	mutate.Run(world, time.Second)
+	watch.Run(world, time.Second)
+	assert.ElementsMatch(t, []wecs.Entity{x, y, z}, changed)
+	assert.Equal(t, map[wecs.Entity]int{x: 1, y: 1, z: 1}, sets)
+}
+
+func TestAccessHookCommands(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Body := wecs.NewComponent[uint32](registry, "body")
+	Shape := wecs.NewComponent[uint32](registry, "shape")
+
+	// hooks queue structural changes on the world, which are applied after the buffer being flushed
+	Body.OnAdd(world, func(world *wecs.World, entity wecs.Entity, data *uint32) {
+		world.Commands().Set(entity, Shape.With(*data*10))
+	})
+	Body.OnRemove(world, func(world *wecs.World, entity wecs.Entity, data *uint32) {
+		world.Commands().Remove(entity, Shape)
+	})
+
+	entity := world.New(Body.With(4))
+	assert.False(t, Shape.Has(world, entity))
+	world.EmptyDeleteQueue()
+	assert.Equal(t, uint32(40), *Shape.Get(world, entity))
+
+	spawner := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		world.Commands().New(Body.With(7))
+		world.Commands().Remove(entity, Body)
+	})
+	spawner.Run(world, time.Second)
+
+	assert.False(t, Shape.Has(world, entity))
+	shapes := []uint32{}
+	for _, shape := range wecs.NewPair(Body, Shape).Query(world, wecs.NewFilter()) {
+		shapes = append(shapes, *shape)
+	}
+	assert.Equal(t, []uint32{70}, shapes)
+}
+
+func TestAccessObserve(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Name := wecs.NewComponent[string](registry, "name")
+	Player := wecs.NewTag(registry, "player")
+
+	bus := wecs.NewBus[wecs.LifecycleEvent]()
+	events := []wecs.LifecycleEvent{}
+	all := []wecs.LifecycleEvent{}
+	bus.Listen(func(event wecs.LifecycleEvent) {
+		events = append(events, event)
+		all = append(all, event)
+	})
+	world.Observe(&bus, wecs.NewFilter().IncludeExact(Player))
+
+	// a system consumes the same events through a pipe, on it's own time
+	pipe := bus.NewPipe()
+	consumed := []wecs.LifecycleEvent{}
+	consumer := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		for event := range pipe.Iter() {
+			consumed = append(consumed, event)
+		}
+	})
+
+	// entities that never match aren't published
+	world.New(Integer)
+	assert.Empty(t, events)
+
+	player := world.New(Integer, Player)
+	assert.Equal(t, []wecs.LifecycleEvent{
+		{Kind: wecs.EntitySpawned, Entity: player},
+		{Kind: wecs.ComponentAdded, Entity: player, Part: Integer.PartId()},
+		{Kind: wecs.TagAdded, Entity: player, Part: Player.PartId()},
+	}, events)
+
+	// additions match after the change, removals before it
+	events = nil
+	other := world.New(Name)
+	Player.Add(world, other)
+	Name.Delete(world, other)
+	Player.Delete(world, other)
+	Name.Add(world, other)
+	assert.Equal(t, []wecs.LifecycleEvent{
+		{Kind: wecs.TagAdded, Entity: other, Part: Player.PartId()},
+		{Kind: wecs.ComponentRemoved, Entity: other, Part: Name.PartId()},
+		{Kind: wecs.TagRemoved, Entity: other, Part: Player.PartId()},
+	}, events)
+
+	events = nil
+	world.Delete(player)
+	assert.Equal(t, []wecs.LifecycleEvent{
+		{Kind: wecs.ComponentRemoved, Entity: player, Part: Integer.PartId()},
+		{Kind: wecs.TagRemoved, Entity: player, Part: Player.PartId()},
+		{Kind: wecs.EntityDeleted, Entity: player},
+	}, events)
+
+	// changes applied by commands and query mutations are published too
+	events = nil
+	world.Commands().NewBatch(2, Player)
+	world.EmptyDeleteQueue()
+	world.MutateQuery(wecs.NewFilter().IncludeExact(Player)).Add(Name).Apply()
+	assert.Len(t, events, 6)
+	for _, event := range events[4:] {
+		assert.Equal(t, wecs.ComponentAdded, event.Kind)
+		assert.Equal(t, Name.PartId(), event.Part)
+	}
+
+	consumer.Run(world, time.Second)
+	assert.Len(t, consumed, 15)
+	assert.Equal(t, all, consumed)
+
+	events = nil
+	world.StopObserving(&bus)
+	world.New(Player)
+	assert.Empty(t, events)
+
+	consumer.Run(world, time.Second)
+	assert.Len(t, consumed, 15)
+}
@@ -2,15 +2,20 @@ package main
 
 import (
 	"iter"
+	"slices"
 
 	"github.com/averagestardust/wecs/internal/ring"
 )
 
 // A generic bus to pass events around the application.
+// Events are queued until every pipe has consumed them, and the queue is compacted as they're consumed,
+// once it has stayed under a quarter full for as many events as it can hold.
 type Bus[Event any] struct {
 	listeners  []func(event Event)
 	pipes      []*Pipe[Event]
 	eventQueue *ring.Ring[Event]
+	consumed   int    // events consumed since the queue was last checked for compaction
+	peak       uint64 // the most events queued since the queue was last checked for compaction
 }
 
 // A pipe that can consume events from a bus on it's own time.
@@ -28,13 +33,16 @@ func NewBus[Event any]() Bus[Event] {
 	}
 }
 
-// Create a pipe that can consume events from a bus on it's own time.
+// Create a pipe that can consume events from a bus on it's own time, starting with the next event published.
 // Once a bus has a pipe it must queue events until all pipes have consumed them.
 func (bus *Bus[Event]) NewPipe() *Pipe[Event] {
-	return &Pipe[Event]{
+	pipe := &Pipe[Event]{
 		bus:       bus,
 		nextEvent: bus.eventQueue.Head(),
 	}
+
+	bus.pipes = append(bus.pipes, pipe)
+	return pipe
 }
 
 // Add a listener to a bus that immediately is called when events are published.
@@ -50,6 +58,7 @@ func (bus *Bus[Event]) Publish(event Event) {
 
 	if len(bus.pipes) > 0 {
 		bus.eventQueue.Enqueue(event)
+		bus.peak = max(bus.peak, bus.eventQueue.Size())
 	}
 }
 
@@ -63,23 +72,51 @@ func (bus *Bus[Event]) PublishBatch(events []Event) {
 
 	if len(bus.pipes) > 0 {
 		bus.eventQueue.EnqueueBatch(events)
+		bus.peak = max(bus.peak, bus.eventQueue.Size())
 	}
 }
 
 // Delete events that have been consumed by all pipes on the bus.
 func (bus *Bus[Event]) dropConsumedQueue() {
-	lastAccessibleEvent := bus.pipes[0].nextEvent
-	for _, pipe := range bus.pipes[1:] {
+	lastAccessibleEvent := bus.eventQueue.Head()
+	for _, pipe := range bus.pipes {
 		lastAccessibleEvent = min(lastAccessibleEvent, pipe.nextEvent)
 	}
 
+	tail := bus.eventQueue.Tail()
 	bus.eventQueue.DropUntil(lastAccessibleEvent)
+	bus.consumed += int(bus.eventQueue.Tail() - tail)
+
+	// only compact once the queue has been mostly empty for a while, so a steady flow of events doesn't reallocate it
+	capacity := bus.eventQueue.Capacity()
+	if bus.consumed < capacity {
+		return
+	}
+
+	if capacity > minQueueCapacity && bus.peak <= uint64(capacity/4) {
+		bus.eventQueue.Compact(max(minQueueCapacity, int(bus.peak)*2))
+	}
+
+	bus.consumed = 0
+	bus.peak = bus.eventQueue.Size()
+}
+
+// The capacity the event queue of a bus is never compacted below.
+const minQueueCapacity = 64
+
+// Release the memory held by the event queue straight away, keeping room for at least twice the events still queued.
+// Buses compact as events are consumed and when pipes are closed, so this is only needed to release memory sooner.
+func (bus *Bus[Event]) Compact() {
+	bus.eventQueue.Compact(minQueueCapacity)
+	bus.consumed = 0
+	bus.peak = bus.eventQueue.Size()
 }
 
 // Get an iterator of events queued on a pipe.
+// Closed pipes have no events.
 func (pipe *Pipe[Event]) Iter() iter.Seq[Event] {
 	return func(yield func(Event) bool) {
-		for {
+		for pipe.bus != nil {
 			event, success := pipe.bus.eventQueue.Peek(pipe.nextEvent)
 			if !success {
 				break
@@ -95,7 +132,12 @@ func (pipe *Pipe[Event]) Iter() iter.Seq[Event] {
 }
 
 // Get one event from a pipe.
+// Fails if there are no events queued or the pipe is closed.
 func (pipe *Pipe[Event]) Pop() (event Event, success bool) {
+	if pipe.bus == nil {
+		return event, false
+	}
+
 	event, success = pipe.bus.eventQueue.Peek(pipe.nextEvent)
 
 	if success {
@@ -108,22 +150,22 @@ func (pipe *Pipe[Event]) Pop() (event Event, success bool) {
 
 // Close a pipe when it is no longer needed.
 // This frees a bus to free unconsumed events.
+// Closing a pipe more than once has no effect.
 func (pipe *Pipe[Event]) Close() {
-	// find index
-	var index int
-	for i, otherPipe := range pipe.bus.pipes {
-		if otherPipe == pipe {
-			index = i
-			break
-		}
+	bus := pipe.bus
+	if bus == nil {
+		return
 	}
 
-	// shift all down, overwriting pipe
-	for i := index + 1; i < len(pipe.bus.pipes); i++ {
-		pipe.bus.pipes[i-1] = pipe.bus.pipes[i]
+	index := slices.Index(bus.pipes, pipe)
+	if index == -1 {
+		return
 	}
 
-	// drop last pipe
-	pipe.bus.pipes = pipe.bus.pipes[0 : len(pipe.bus.pipes)-1]
+	bus.pipes = slices.Delete(bus.pipes, index, index+1)
 	pipe.bus = nil
+
+	// a closed pipe is often one that fell behind, leaving a large queue
+	bus.dropConsumedQueue()
+	bus.Compact()
 }
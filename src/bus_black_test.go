@@ -0,0 +1,195 @@
+package main_test
+
+import (
+	"slices"
+	"testing"
+
+	wecs "github.com/averagestardust/wecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusListen(t *testing.T) {
+	bus := wecs.NewBus[int]()
+
+	heard := []int{}
+	bus.Listen(func(event int) {
+		heard = append(heard, event)
+	})
+
+	bus.Publish(1)
+	bus.PublishBatch([]int{2, 3})
+	assert.Equal(t, []int{1, 2, 3}, heard)
+}
+
+func TestBusPipe(t *testing.T) {
+	bus := wecs.NewBus[int]()
+
+	// events published before a pipe exists aren't queued on it
+	bus.Publish(0)
+	pipe := bus.NewPipe()
+
+	bus.Publish(1)
+	bus.PublishBatch([]int{2, 3, 4})
+
+	event, success := pipe.Pop()
+	assert.True(t, success)
+	assert.Equal(t, 1, event)
+	assert.Equal(t, []int{2, 3, 4}, slices.Collect(pipe.Iter()))
+
+	_, success = pipe.Pop()
+	assert.False(t, success)
+	assert.Empty(t, slices.Collect(pipe.Iter()))
+
+	bus.Publish(5)
+	assert.Equal(t, []int{5}, slices.Collect(pipe.Iter()))
+}
+
+func TestBusPipeRates(t *testing.T) {
+	bus := wecs.NewBus[int]()
+	fast := bus.NewPipe()
+	slow := bus.NewPipe()
+
+	fastEvents := []int{}
+	slowEvents := []int{}
+	for i := range 1000 {
+		bus.Publish(i)
+
+		// the fast pipe reads every event, the slow pipe reads in bursts
+		fastEvents = append(fastEvents, slices.Collect(fast.Iter())...)
+		if i%100 == 99 {
+			for event := range slow.Iter() {
+				slowEvents = append(slowEvents, event)
+				if len(slowEvents)%30 == 0 {
+					break
+				}
+			}
+		}
+	}
+	slowEvents = append(slowEvents, slices.Collect(slow.Iter())...)
+
+	expected := []int{}
+	for i := range 1000 {
+		expected = append(expected, i)
+	}
+	assert.Equal(t, expected, fastEvents)
+	assert.Equal(t, expected, slowEvents)
+}
+
+func TestBusPipeClose(t *testing.T) {
+	bus := wecs.NewBus[int]()
+	first := bus.NewPipe()
+	second := bus.NewPipe()
+	third := bus.NewPipe()
+
+	bus.PublishBatch([]int{1, 2, 3})
+	event, _ := first.Pop()
+	assert.Equal(t, 1, event)
+
+	// closing a pipe mid-stream leaves the other pipes reading where they were
+	first.Close()
+	_, success := first.Pop()
+	assert.False(t, success)
+	assert.Empty(t, slices.Collect(first.Iter()))
+
+	event, _ = second.Pop()
+	assert.Equal(t, 1, event)
+
+	// closing again, or closing after other pipes changed, has no effect on them
+	first.Close()
+	third.Close()
+	third.Close()
+
+	bus.Publish(4)
+	assert.Equal(t, []int{2, 3, 4}, slices.Collect(second.Iter()))
+
+	second.Close()
+	bus.Publish(5)
+
+	// new pipes still work once every other pipe is closed
+	pipe := bus.NewPipe()
+	bus.Publish(6)
+	assert.Equal(t, []int{6}, slices.Collect(pipe.Iter()))
+}
+
+func TestBusSteadyAllocations(t *testing.T) {
+	bus := wecs.NewBus[int]()
+	pipe := bus.NewPipe()
+
+	frame := func() {
+		for i := range 500 {
+			bus.Publish(i)
+		}
+		for {
+			if _, success := pipe.Pop(); !success {
+				break
+			}
+		}
+	}
+	frame()
+
+	// once the queue has grown, draining it each frame doesn't shrink it to be grown again
+	assert.Zero(t, testing.AllocsPerRun(10, frame))
+
+	// queues keep working after being compacted
+	bus.Compact()
+	frame()
+	bus.Compact()
+	bus.Publish(0)
+	event, _ := pipe.Pop()
+	assert.Equal(t, 0, event)
+}
+
+func TestBusCompactsAfterBurst(t *testing.T) {
+	bus := wecs.NewBus[int]()
+	pipe := bus.NewPipe()
+
+	drain := func() {
+		for {
+			if _, success := pipe.Pop(); !success {
+				break
+			}
+		}
+	}
+	burst := make([]int, 10000)
+	few := make([]int, 2)
+
+	bus.PublishBatch(burst)
+	drain()
+
+	// bursts don't reallocate the queue they grew
+	assert.Zero(t, testing.AllocsPerRun(5, func() {
+		bus.PublishBatch(burst)
+		drain()
+	}))
+
+	// but once a pipe has read a few events at a time for long enough the queue shrinks, so the next burst grows it again
+	assert.NotZero(t, testing.AllocsPerRun(5, func() {
+		for range 20000 {
+			bus.PublishBatch(few)
+			drain()
+		}
+
+		bus.PublishBatch(burst)
+		drain()
+	}))
+}
+
+func TestBusObserve(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+
+	bus := wecs.NewBus[wecs.LifecycleEvent]()
+	world.Observe(&bus, wecs.NewFilter())
+	pipe := bus.NewPipe()
+
+	entity := world.New(Integer)
+	world.Delete(entity)
+
+	kinds := []wecs.LifecycleKind{}
+	for event := range pipe.Iter() {
+		assert.Equal(t, entity, event.Entity)
+		kinds = append(kinds, event.Kind)
+	}
+	assert.Equal(t, []wecs.LifecycleKind{wecs.EntitySpawned, wecs.ComponentAdded, wecs.ComponentRemoved, wecs.EntityDeleted}, kinds)
+}
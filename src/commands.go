@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A buffer of structural changes recorded while iterating a world, applied later with Flush.
+// Commands are played back in the order they were recorded.
+type Commands struct {
+	commands []command
+	deleted  map[Entity]struct{}
+}
+
+type commandKind uint8
+
+const (
+	newCommand commandKind = iota
+	deleteCommand
+	mutateCommand
+)
+
+// A single recorded change.
+type command struct {
+	kind    commandKind
+	entity  Entity
+	count   int
+	added   []storage.Part
+	removed []storage.Part
+}
+
+// Create an empty command buffer.
+func NewCommands() *Commands {
+	return &Commands{
+		deleted: map[Entity]struct{}{},
+	}
+}
+
+// Record creating a new entity out of an arbitrary list of components/tags.
+// Components given with Component.With start with that data, others start empty.
+func (commands *Commands) New(parts ...storage.Part) {
+	commands.NewBatch(1, parts...)
+}
+
+// Record creating multiple identical new entities out of an arbitrary list of components/tags.
+func (commands *Commands) NewBatch(count int, parts ...storage.Part) {
+	commands.commands = append(commands.commands, command{kind: newCommand, count: count, added: parts})
+}
+
+// Record deleting an entity.
+func (commands *Commands) Delete(entity Entity) {
+	commands.commands = append(commands.commands, command{kind: deleteCommand, entity: entity})
+	commands.deleted[entity] = struct{}{}
+}
+
+// Record adding components/tags to an entity, components given with Component.With have their data set.
+func (commands *Commands) Add(entity Entity, parts ...storage.Part) {
+	commands.commands = append(commands.commands, command{kind: mutateCommand, entity: entity, added: parts})
+}
+
+// Record removing components/tags from an entity.
+func (commands *Commands) Remove(entity Entity, parts ...storage.Part) {
+	commands.commands = append(commands.commands, command{kind: mutateCommand, entity: entity, removed: parts})
+}
+
+// Record setting the data of components given with Component.With, adding any the entity doesn't have.
+func (commands *Commands) Set(entity Entity, values ...storage.Part) {
+	commands.Add(entity, values...)
+}
+
+// Check if an entity has been recorded for deletion.
+func (commands *Commands) Deleted(entity Entity) bool {
+	_, deleted := commands.deleted[entity]
+	return deleted
+}
+
+// Get the number of recorded commands.
+func (commands *Commands) Len() int {
+	return len(commands.commands)
+}
+
+// Apply every recorded command to a world in the order they were recorded, then empty the buffer.
+// Commands recorded while flushing, such as by hooks, are applied after the others.
+// Commands on entities that no longer exist are skipped.
+func (commands *Commands) Flush(world *World) {
+	for i := 0; i < len(commands.commands); i++ {
+		command := commands.commands[i]
+		switch command.kind {
+		case newCommand:
+			world.NewBatch(command.count, command.added...)
+		case deleteCommand:
+			world.Delete(command.entity)
+		case mutateCommand:
+			world.Mutate(command.entity).Add(command.added...).Remove(command.removed...).Apply()
+		}
+	}
+
+	commands.Clear()
+}
+
+// Discard every recorded command without applying them.
+func (commands *Commands) Clear() {
+	// drop references to parts so their values can be collected
+	clear(commands.commands)
+	commands.commands = commands.commands[:0]
+	clear(commands.deleted)
+}
+
+// get the entities recorded for deletion, in the order they were recorded
+func (commands *Commands) deletions() (entities []Entity) {
+	for _, command := range commands.commands {
+		if command.kind == deleteCommand {
+			entities = append(entities, command.entity)
+		}
+	}
+
+	return entities
+}
@@ -8,35 +8,59 @@ import (
 	"github.com/averagestardust/wecs/internal/storage"
 )
 
-// An integer uniquely identifying a component type.
+// An integer uniquely identifying a component type within a registry.
 // Components should store related data on an entity.
 // Should be created in a static order during world initialization.
 type Component[Data any] storage.PartId
 
-// The next component id to be assigned when a component is created.
-// Increments from zero.
-var nextComponent storage.PartId = 0
-
-// Create a new component type from a data type.
+// Create a new component type from a data type on a registry.
 // The data type should store related data, probably with a struct.
+// Data types containing pointers, strings, slices or maps are kept in typed storage the garbage collector can see,
+// and are saved by value with their exported fields, so SerializeWorld fails if they have unexported fields.
+// The name must be unique within the registry and stay the same between versions, as saves match components by name.
 // Should be used in a static order during world initialization.
-func NewComponent[Data any]() Component[Data] {
-	var data Data
-	typ := reflect.TypeOf(data)
+func NewComponent[Data any](registry *Registry, name string) Component[Data] {
+	typ := reflect.TypeFor[Data]()
+	return Component[Data](registry.parts.NewComponent(name, typ))
+}
+
+// A component along with data to give it, usable as a part when creating entities.
+type ComponentValue[Data any] struct {
+	component Component[Data]
+	value     Data
+}
 
-	storage.NewPartType(nextComponent, typ)
-	component := Component[Data](nextComponent)
+// A part that carries data to write once an entity has it.
+type valuePart interface {
+	storage.Part
+	write(page *storage.Page, index int)
+}
+
+// Pair a component with data, to create entities with the component already set.
+func (component Component[Data]) With(value Data) ComponentValue[Data] {
+	return ComponentValue[Data]{component, value}
+}
 
-	nextComponent++
-	return component
+// Get the part id of the component.
+func (value ComponentValue[Data]) PartId() storage.PartId {
+	return storage.PartId(value.component)
+}
+
+func (value ComponentValue[Data]) write(page *storage.Page, index int) {
+	values, exists := column[Data](page, storage.PartId(value.component))
+	if exists {
+		values[index] = value.value
+	}
 }
 
 // Remove a component from an entity.
 func (component Component[Data]) Delete(world *World, entity Entity) (success bool) {
-	return world.store.DeletePart(storage.EntityId(entity), component)
+	world.beforeRemove(entity, []storage.Part{component})
+	return world.store.DeletePart(storage.EntityId(entity), component, world.changeTick())
 }
 
 // Get the data of a component from an entity.
+// Writes through it aren't seen by Filter.Changed, use Mut to change data.
 func (component Component[Data]) Get(world *World, entity Entity) (data *Data) {
 	bytes := world.store.GetComponent(storage.EntityId(entity), storage.PartId(component))
 	if bytes == nil {
@@ -46,6 +70,24 @@ func (component Component[Data]) Get(world *World, entity Entity) (data *Data) {
 	return (*Data)(unsafe.Pointer(&bytes[0]))
 }
 
+// Get the data of a component from an entity to change it, marking the component as changed.
+func (component Component[Data]) Mut(world *World, entity Entity) (data *Data) {
+	page, index, exists := world.store.Locate(storage.EntityId(entity))
+	if !exists {
+		return nil
+	}
+
+	values, exists := column[Data](page, storage.PartId(component))
+	if !exists {
+		return nil
+	}
+
+	ticks, _ := page.ChangeTicks(storage.PartId(component))
+	ticks[index].Changed = world.changeTick()
+
+	return &values[index]
+}
+
 // Check if a entity has a component.
 func (component Component[Data]) Has(world *World, entity Entity) (success bool) {
 	return world.store.HasPart(storage.EntityId(entity), component)
@@ -53,16 +95,40 @@ func (component Component[Data]) Has(world *World, entity Entity) (success bool)
 
 // Add a component with empty data to an entity.
 func (component Component[Data]) Add(world *World, entity Entity) (success bool) {
-	return world.store.AddPart(storage.EntityId(entity), component)
+	before, _ := world.store.PartsOf(storage.EntityId(entity))
+	if !world.store.AddPart(storage.EntityId(entity), component, world.changeTick()) {
+		return false
+	}
+
+	world.afterAdd(entity, before, nil)
+	return true
+}
+
+// Set the data of a component on an entity, adding the component first if the entity doesn't have it.
+// Returns true if the component was added, changing the archetype of the entity.
+func (component Component[Data]) Set(world *World, entity Entity, value Data) (added bool) {
+	if data := component.Mut(world, entity); data != nil {
+		*data = value
+		world.afterSet(entity, storage.PartId(component))
+		return false
+	}
+
+	// add the component with it's data, so add hooks see the data
+	return world.Mutate(entity).Add(component.With(value)).Apply()
 }
 
 // Return an iterator of data from one component type from all entities that match a filter.
-func (component Component[Data]) Query(world *World, filter Filter) iter.Seq[*Data] {
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (component Component[Data]) Query(world *World, filter Matcher) iter.Seq[*Data] {
 	return func(yield func(*Data) bool) {
-		for page := range filter.filter(world.store) {
-			for bytes := range page.GetComponentIter(storage.PartId(component)) {
-				data := (*Data)(unsafe.Pointer(&bytes))
-				if !yield(data) {
+		for page, rows := range filter.filter(world) {
+			values, _ := column[Data](page, storage.PartId(component))
+			for i := range values {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				if !yield(&values[i]) {
 					return
 				}
 			}
@@ -70,6 +136,83 @@ func (component Component[Data]) Query(world *World, filter Filter) iter.Seq[*Da
 	}
 }
 
+// Return an iterator of data from one component type from all entities that match a filter, marking each as changed.
+// The data points into the world, so writes through it are kept.
+func (component Component[Data]) QueryMut(world *World, filter Matcher) iter.Seq[*Data] {
+	return func(yield func(*Data) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			values, _ := column[Data](page, storage.PartId(component))
+			ticks, _ := page.ChangeTicks(storage.PartId(component))
+			for i := range values {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				ticks[i].Changed = tick
+				if !yield(&values[i]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from one component type, from all entities that match a filter.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (component Component[Data]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, *Data] {
+	return func(yield func(Entity, *Data) bool) {
+		for page, rows := range filter.filter(world) {
+			values, _ := column[Data](page, storage.PartId(component))
+			for i := range values {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				if !yield(Entity(page.Entities[i]), &values[i]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Return an iterator of entities the component was removed from, including deleted entities, since the running system last ran.
+// Yields the last data the component had, which shouldn't be kept after the system finishes.
+// Removals are recorded once the component is registered with RecordRemoved, or otherwise from the first time this is used on a world,
+// and kept until every system has seen them.
+func (component Component[Data]) Removed(world *World) iter.Seq2[Entity, *Data] {
+	return func(yield func(Entity, *Data) bool) {
+		entities, ticks, pointer := world.store.Removals(storage.PartId(component))
+		values := unsafe.Slice((*Data)(pointer), len(entities))
+
+		for i, entity := range entities {
+			if ticks[i] > world.lastRun && !yield(Entity(entity), &values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Record the entities the component is removed from in every world using the registry, from the moment the world is created.
+// Without this removals are only recorded once Removed is first used, missing those made before it.
+// Should be used during world initialization.
+func (component Component[Data]) RecordRemoved(registry *Registry) {
+	registry.parts.RecordRemovals(storage.PartId(component))
+}
+
+// Get a typed view of the data of a component on a page.
+// Fails if the page doesn't have the component.
+func column[Data any](page *storage.Page, partId storage.PartId) (values []Data, exists bool) {
+	pointer, exists := page.Column(partId)
+	if !exists {
+		return nil, false
+	}
+
+	return unsafe.Slice((*Data)(pointer), page.Size), true
+}
+
 // Get the part id.
 func (component Component[Data]) PartId() storage.PartId {
 	return storage.PartId(component)
@@ -6,20 +6,24 @@ import (
 	"github.com/averagestardust/wecs/internal/storage"
 )
 
-// An integer uniquely identifying an entity over the life of the program
+// An integer uniquely identifying an entity over the life of the program.
+// Packs a recycled index with a generation, so handles to deleted entities never refer to newer entities.
 type Entity storage.EntityId
 
 // Check if an entity exists.
 func (world *World) Exists(entity Entity) bool {
-	_, exists := world.store.Entries[storage.EntityId(entity)]
-	return exists
+	return world.store.Exists(storage.EntityId(entity))
 }
 
 // Return an iterator of all the entities that match the filter.
-func (world *World) Query(filter Filter) iter.Seq[Entity] {
+func (world *World) Query(filter Matcher) iter.Seq[Entity] {
 	return func(yield func(Entity) bool) {
-		for page := range filter.filter(world.store) {
-			for _, entity := range page.Entities {
+		for page, rows := range filter.filter(world) {
+			for i, entity := range page.Entities {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
 				if !yield(Entity(entity)) {
 					return
 				}
@@ -30,28 +34,70 @@ func (world *World) Query(filter Filter) iter.Seq[Entity] {
 
 // Immediately delete an entity, without queuing it.
 func (world *World) Delete(entity Entity) {
-	world.store.Delete(storage.EntityId(entity))
+	world.beforeDelete(entity)
+	world.store.Delete(storage.EntityId(entity), world.changeTick())
 }
 
 // Create a new entity out of an arbitrary list of components/tags.
+// Components given with Component.With start with that data, others start empty.
 func (world *World) New(parts ...storage.Part) Entity {
 	archetype := world.store.NewArchetype(parts)
-	entity := world.store.Grow(archetype, 1)
+	entities := world.store.Grow(archetype, 1, world.changeTick())
+	world.writeValues(entities, parts)
 
-	return Entity(entity)
+	return Entity(entities[0])
 }
 
 // Create multiple identical new entities out of an arbitrary list of components/tags.
+// Components given with Component.With start with that data, others start empty.
 // Returns a iterator of the new entities.
 func (world *World) NewBatch(count int, parts ...storage.Part) iter.Seq[Entity] {
 	archetype := world.store.NewArchetype(parts)
-	firstEntity := world.store.Grow(archetype, count)
+	entities := world.store.Grow(archetype, count, world.changeTick())
+	world.writeValues(entities, parts)
 
 	return func(yield func(Entity) bool) {
-		for i := range count {
-			if !yield(Entity(firstEntity + storage.EntityId(i))) {
+		for _, entity := range entities {
+			if !yield(Entity(entity)) {
 				return
 			}
 		}
 	}
 }
+
+// Write the data of parts created with Component.With into new entities that are next to each other in one page, then call their hooks.
+func (world *World) writeValues(entities []storage.EntityId, parts []storage.Part) {
+	if len(entities) == 0 {
+		return
+	}
+
+	page, firstIndex, _ := world.store.Locate(entities[0])
+	world.writeValuesAt(page, firstIndex, len(entities), parts)
+
+	archetype, _ := world.store.PartsOf(entities[0])
+	world.publish(EntitySpawned, 0, page, firstIndex, len(entities), archetype)
+	world.afterChange(page, firstIndex, len(entities), nil, archetype, parts)
+}
+
+// Write the data of parts created with Component.With into a range of rows on a page, marking them as changed.
+func (world *World) writeValuesAt(page *storage.Page, firstIndex int, count int, parts []storage.Part) {
+	tick := world.changeTick()
+
+	for _, part := range parts {
+		value, isValue := part.(valuePart)
+		if !isValue {
+			continue
+		}
+
+		// parts that were also removed aren't on the page
+		ticks, exists := page.ChangeTicks(part.PartId())
+		if !exists {
+			continue
+		}
+
+		for i := firstIndex; i < firstIndex+count; i++ {
+			value.write(page, i)
+			ticks[i].Changed = tick
+		}
+	}
+}
@@ -9,16 +9,33 @@ import (
 // A set of checks or "layers" to filter entities.
 type Filter []layer
 
+// A way to find the pages of entities to query, either a Filter or a CachedQuery.
+type Matcher interface {
+	// find the matching pages, along with a check for their rows that is nil when every row matches
+	filter(world *World) iter.Seq2[*storage.Page, func(index int) bool]
+}
+
 // A unique check for an entity archetype.
 type layer interface {
 	check(archetype *storage.Signature) bool
 }
 
+// A check for the rows of an archetype, for checks that can't be decided by the archetype alone.
+// The archetype check must pass for every row that passes.
+type rowLayer interface {
+	layer
+	checkRow(world *World, archetype *storage.Signature, page *storage.Page, index int) bool
+}
+
 type exactlyLayer storage.Signature
 type includeExactLayer storage.Signature
 type includeAnyLayer storage.Signature
 type excludeAnyLayer storage.Signature
 type excludeExactLayer storage.Signature
+type orLayer []Filter
+type notLayer Filter
+type addedLayer storage.PartId
+type changedLayer storage.PartId
 
 // Create a new set of checks to filter entities.
 func NewFilter() Filter {
@@ -45,6 +62,57 @@ func (layer excludeExactLayer) check(archetype *storage.Signature) bool {
 	return !archetype.ContainsAll(storage.Signature(layer))
 }
 
+func (layer orLayer) check(archetype *storage.Signature) bool {
+	for _, filter := range layer {
+		if filter.match(archetype) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (layer orLayer) checkRow(world *World, archetype *storage.Signature, page *storage.Page, index int) bool {
+	for _, filter := range layer {
+		if filter.match(archetype) && filter.matchRow(world, archetype, page, index) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (layer notLayer) check(archetype *storage.Signature) bool {
+	if Filter(layer).hasRows() {
+		// some rows of a matching archetype may still fail the filter
+		return true
+	}
+
+	return !Filter(layer).match(archetype)
+}
+
+func (layer notLayer) checkRow(world *World, archetype *storage.Signature, page *storage.Page, index int) bool {
+	return !(Filter(layer).match(archetype) && Filter(layer).matchRow(world, archetype, page, index))
+}
+
+func (layer addedLayer) check(archetype *storage.Signature) bool {
+	return archetype.ContainsSingle(storage.PartId(layer))
+}
+
+func (layer addedLayer) checkRow(world *World, archetype *storage.Signature, page *storage.Page, index int) bool {
+	ticks, exists := page.ChangeTicks(storage.PartId(layer))
+	return exists && ticks[index].Added > world.lastRun
+}
+
+func (layer changedLayer) check(archetype *storage.Signature) bool {
+	return archetype.ContainsSingle(storage.PartId(layer))
+}
+
+func (layer changedLayer) checkRow(world *World, archetype *storage.Signature, page *storage.Page, index int) bool {
+	ticks, exists := page.ChangeTicks(storage.PartId(layer))
+	return exists && ticks[index].Changed > world.lastRun
+}
+
 // Add a check for entities that all the components exactly match some components.
 func (filter Filter) Exactly(components ...storage.Part) Filter {
 	filter = append(filter, exactlyLayer(storage.NewSignature(components)))
@@ -75,24 +143,112 @@ func (filter Filter) ExcludeExact(components ...storage.Part) Filter {
 	return filter
 }
 
-// Filter through the archetypes on a storage, and return the an iterator of matching entities.
-func (layers Filter) filter(store *storage.Store) iter.Seq[*storage.Page] {
-	return func(yield func(page *storage.Page) bool) {
-	pageLoop:
-		for archetypeId, page := range store.Pages {
-			// check layers
-			for _, layer := range layers {
-				archetype := &store.Archetypes[archetypeId]
-				if !layer.check(archetype) {
-					continue pageLoop
+// Add a check for entities that had a component or tag added since the running system last ran.
+// Outside of a system every component counts as added.
+func (filter Filter) Added(component storage.Part) Filter {
+	filter = append(filter, addedLayer(component.PartId()))
+	return filter
+}
+
+// Add a check for entities that had a component added or changed since the running system last ran.
+// Components are changed by Set, Mut and the QueryMut methods of components and queries, not by writing through Get or Query.
+// Tags are never changed, so they count as changed when they're added.
+// Outside of a system every component counts as changed.
+func (filter Filter) Changed(component storage.Part) Filter {
+	filter = append(filter, changedLayer(component.PartId()))
+	return filter
+}
+
+// Create a filter for entities that match all of some filters.
+func And(filters ...Filter) Filter {
+	filter := Filter{}
+	for _, other := range filters {
+		filter = append(filter, other...)
+	}
+
+	return filter
+}
+
+// Create a filter for entities that match at least one of some filters.
+func Or(filters ...Filter) Filter {
+	return Filter{orLayer(filters)}
+}
+
+// Create a filter for entities that don't match a filter.
+func Not(filter Filter) Filter {
+	return Filter{notLayer(filter)}
+}
+
+// Check if an archetype passes every layer of the filter.
+func (layers Filter) match(archetype *storage.Signature) bool {
+	for _, layer := range layers {
+		if !layer.check(archetype) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check if any layer of the filter has to check rows.
+func (layers Filter) hasRows() bool {
+	for _, layer := range layers {
+		switch layer := layer.(type) {
+		case orLayer:
+			for _, filter := range layer {
+				if filter.hasRows() {
+					return true
 				}
 			}
+		case notLayer:
+			if Filter(layer).hasRows() {
+				return true
+			}
+		case rowLayer:
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if a row of a matching archetype passes every layer of the filter.
+func (layers Filter) matchRow(world *World, archetype *storage.Signature, page *storage.Page, index int) bool {
+	for _, layer := range layers {
+		if layer, isRowLayer := layer.(rowLayer); isRowLayer && !layer.checkRow(world, archetype, page, index) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get a check for the rows of a matching page, or nil if every row matches.
+func (layers Filter) rows(world *World, archetype *storage.Signature, page *storage.Page) func(index int) bool {
+	if !layers.hasRows() {
+		return nil
+	}
+
+	return func(index int) bool {
+		return layers.matchRow(world, archetype, page, index)
+	}
+}
+
+// Filter through the archetypes on a storage, and return the an iterator of matching pages.
+func (layers Filter) filter(world *World) iter.Seq2[*storage.Page, func(index int) bool] {
+	store := world.store
+
+	return func(yield func(page *storage.Page, rows func(index int) bool) bool) {
+		for archetypeId, page := range store.Pages {
+			archetype := &store.Archetypes[archetypeId]
+			if page == nil || !layers.match(archetype) {
+				continue
+			}
 
 			// yield matching page
-			if !yield(page) {
+			if !yield(page, layers.rows(world, archetype, page)) {
 				return
 			}
 		}
-
 	}
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A function called when a component of an entity is added, removed or set, with a pointer to it's data.
+// Hooks may run in the middle of changing a page, so structural changes must be queued with World.Commands instead of made directly.
+// Queued changes are applied with the rest of the buffer.
+// Hooks called while flushing queue their changes on the world's own buffer, so they're applied once the buffer being flushed is done,
+// after the other commands queued on the world.
+type Hook[Data any] func(world *World, entity Entity, data *Data)
+
+// A hook without it's data type, called with the row of the entity
+type rowHook func(world *World, page *storage.Page, index int)
+
+// The hooks and observers registered on a world, shared by the views systems get of it.
+type hooks struct {
+	onAdd     map[storage.PartId][]rowHook
+	onRemove  map[storage.PartId][]rowHook
+	onSet     map[storage.PartId][]rowHook
+	observers []observer
+}
+
+func newHooks() *hooks {
+	return &hooks{
+		onAdd:    map[storage.PartId][]rowHook{},
+		onRemove: map[storage.PartId][]rowHook{},
+		onSet:    map[storage.PartId][]rowHook{},
+	}
+}
+
+// Register a hook called after a component is added to an entity, once data given with Component.With is written.
+func (component Component[Data]) OnAdd(world *World, hook Hook[Data]) {
+	world.hooks.onAdd[storage.PartId(component)] = append(world.hooks.onAdd[storage.PartId(component)], component.rowHook(hook))
+}
+
+// Register a hook called before a component is removed from an entity, including when the entity is deleted.
+// The data is about to be dropped and shouldn't be kept.
+func (component Component[Data]) OnRemove(world *World, hook Hook[Data]) {
+	world.hooks.onRemove[storage.PartId(component)] = append(world.hooks.onRemove[storage.PartId(component)], component.rowHook(hook))
+}
+
+// Register a hook called after the data of a component is written with Component.Set or Component.With.
+// Called after the add hooks when the component is also added.
+func (component Component[Data]) OnSet(world *World, hook Hook[Data]) {
+	world.hooks.onSet[storage.PartId(component)] = append(world.hooks.onSet[storage.PartId(component)], component.rowHook(hook))
+}
+
+func (component Component[Data]) rowHook(hook Hook[Data]) rowHook {
+	return func(world *World, page *storage.Page, index int) {
+		values, _ := column[Data](page, storage.PartId(component))
+		hook(world, Entity(page.Entities[index]), &values[index])
+	}
+}
+
+// Check if anything needs to know about parts being removed.
+func (hooks *hooks) watchingRemovals() bool {
+	return len(hooks.onRemove) != 0 || len(hooks.observers) != 0
+}
+
+// Check if anything needs to know about parts being added or set.
+func (hooks *hooks) watchingAdditions() bool {
+	return len(hooks.onAdd) != 0 || len(hooks.onSet) != 0 || len(hooks.observers) != 0
+}
+
+// Call the remove hooks of parts an entity is about to lose.
+func (world *World) beforeRemove(entity Entity, removed []storage.Part) {
+	if !world.hooks.watchingRemovals() {
+		return
+	}
+
+	before, exists := world.store.PartsOf(storage.EntityId(entity))
+	if !exists {
+		return
+	}
+
+	page, index, _ := world.store.Locate(storage.EntityId(entity))
+	world.beforeChange(page, index, 1, before, before.Change(nil, removed))
+}
+
+// Call the add hooks of parts an entity gained since it had a signature, then the set hooks of the values written.
+func (world *World) afterAdd(entity Entity, before storage.Signature, values []storage.Part) {
+	if !world.hooks.watchingAdditions() {
+		return
+	}
+
+	after, exists := world.store.PartsOf(storage.EntityId(entity))
+	if !exists {
+		return
+	}
+
+	page, index, _ := world.store.Locate(storage.EntityId(entity))
+	world.afterChange(page, index, 1, before, after, values)
+}
+
+// Call the set hooks of a component on an entity.
+func (world *World) afterSet(entity Entity, partId storage.PartId) {
+	if len(world.hooks.onSet[partId]) == 0 {
+		return
+	}
+
+	page, index, _ := world.store.Locate(storage.EntityId(entity))
+	for _, hook := range world.hooks.onSet[partId] {
+		hook(world, page, index)
+	}
+}
+
+// Call the remove hooks of parts in rows of a page that are about to change from one signature to another.
+func (world *World) beforeChange(page *storage.Page, firstIndex int, count int, before storage.Signature, after storage.Signature) {
+	if !world.hooks.watchingRemovals() {
+		return
+	}
+
+	for _, partId := range before {
+		if after.ContainsSingle(partId) {
+			continue
+		}
+
+		for _, hook := range world.hooks.onRemove[partId] {
+			for i := firstIndex; i < firstIndex+count; i++ {
+				hook(world, page, i)
+			}
+		}
+	}
+
+	world.publishRemoved(page, firstIndex, count, before, after)
+}
+
+// Call the remove hooks of every part of an entity about to be deleted.
+func (world *World) beforeDelete(entity Entity) {
+	if !world.hooks.watchingRemovals() {
+		return
+	}
+
+	before, exists := world.store.PartsOf(storage.EntityId(entity))
+	if !exists {
+		return
+	}
+
+	page, index, _ := world.store.Locate(storage.EntityId(entity))
+	world.beforeChange(page, index, 1, before, nil)
+	world.publish(EntityDeleted, 0, page, index, 1, before)
+}
+
+// Call the add hooks of parts in rows of a page that changed from one signature to another, then the set hooks of the values written.
+func (world *World) afterChange(page *storage.Page, firstIndex int, count int, before storage.Signature, after storage.Signature, values []storage.Part) {
+	if !world.hooks.watchingAdditions() {
+		return
+	}
+
+	for _, partId := range after {
+		if before.ContainsSingle(partId) {
+			continue
+		}
+
+		for _, hook := range world.hooks.onAdd[partId] {
+			for i := firstIndex; i < firstIndex+count; i++ {
+				hook(world, page, i)
+			}
+		}
+	}
+
+	world.publishAdded(page, firstIndex, count, before, after)
+
+	for _, part := range values {
+		if _, isValue := part.(valuePart); !isValue || !after.ContainsSingle(part) {
+			continue
+		}
+
+		for _, hook := range world.hooks.onSet[part.PartId()] {
+			for i := firstIndex; i < firstIndex+count; i++ {
+				hook(world, page, i)
+			}
+		}
+	}
+}
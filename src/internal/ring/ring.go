@@ -51,7 +51,12 @@ func (ring *Ring[T]) DropUntil(index uint64) bool {
 		return false
 	}
 
-	ring.tail = max(ring.tail, index)
+	// clear dropped elements so the references they held can be collected
+	var zero T
+	for ; ring.tail < index; ring.tail++ {
+		ring.buffer[ring.tail&(ring.mask())] = zero
+	}
+
 	return true
 }
 
@@ -60,7 +65,9 @@ func (ring *Ring[T]) Dequeue() (element T, success bool) {
 		return element, false
 	}
 
+	var zero T
 	element = ring.buffer[ring.tail&(ring.mask())]
+	ring.buffer[ring.tail&(ring.mask())] = zero
 	ring.tail++
 
 	return element, true
@@ -99,6 +106,10 @@ func (ring *Ring[T]) Enqueue(element T) {
 	ring.head++
 }
 
+func (ring *Ring[T]) Capacity() int {
+	return cap(ring.buffer)
+}
+
 func (ring *Ring[T]) Size() uint64 {
 	return ring.head - ring.tail
 }
@@ -135,6 +146,26 @@ func (ring *Ring[T]) grow() {
 	ring.buffer = newBuffer
 }
 
+// Shrink the buffer while it's at most a quarter full, so memory is released after a burst of elements.
+// The buffer is left at least half empty so it doesn't grow again straight away, and never shrinks below a minimum capacity.
+func (ring *Ring[T]) Compact(minCapacity int) {
+	newCapacity := cap(ring.buffer)
+	for newCapacity/2 >= max(minCapacity, 1) && ring.Size() <= uint64(newCapacity/4) {
+		newCapacity /= 2
+	}
+
+	if newCapacity == cap(ring.buffer) {
+		return
+	}
+
+	newBuffer := make([]T, newCapacity)
+	for i := ring.tail; i < ring.head; i++ {
+		newBuffer[i&uint64(newCapacity-1)] = ring.buffer[i&(ring.mask())]
+	}
+
+	ring.buffer = newBuffer
+}
+
 func (ring *Ring[T]) mask() uint64 {
 	return uint64(cap(ring.buffer)) - 1
 }
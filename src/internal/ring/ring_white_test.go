@@ -57,3 +57,79 @@ func TestRingGrow(t *testing.T) {
 			tail:   2,
 		}, *ring2)
 }
+
+func TestRingCompact(t *testing.T) {
+	ring0 := &Ring[int]{
+		buffer: []int{0, 0, 0, 0, 0, 3, 4, 0},
+		head:   7,
+		tail:   5,
+	}
+
+	ring0.Compact(1)
+	assert.EqualValues(t,
+		Ring[int]{
+			buffer: []int{0, 3, 4, 0},
+			head:   7,
+			tail:   5,
+		}, *ring0)
+
+	ring1 := &Ring[int]{
+		buffer: []int{9, 0, 0, 0, 0, 0, 0, 8},
+		head:   9,
+		tail:   7,
+	}
+
+	ring1.Compact(1)
+	assert.EqualValues(t,
+		Ring[int]{
+			buffer: []int{9, 0, 0, 8},
+			head:   9,
+			tail:   7,
+		}, *ring1)
+
+	// rings more than a quarter full aren't changed
+	ring2 := &Ring[int]{
+		buffer: []int{1, 2, 3, 0},
+		head:   3,
+		tail:   0,
+	}
+
+	ring2.Compact(1)
+	assert.Equal(t, []int{1, 2, 3, 0}, ring2.buffer)
+
+	empty := &Ring[int]{
+		buffer: make([]int, 16),
+		head:   20,
+		tail:   20,
+	}
+
+	empty.Compact(1)
+	assert.Len(t, empty.buffer, 1)
+
+	// never shrinks below the minimum capacity
+	floored := &Ring[int]{
+		buffer: make([]int, 16),
+		head:   20,
+		tail:   19,
+	}
+
+	floored.Compact(8)
+	assert.Len(t, floored.buffer, 8)
+	floored.Compact(8)
+	assert.Len(t, floored.buffer, 8)
+}
+
+func TestRingDropClears(t *testing.T) {
+	ring := &Ring[*int]{
+		buffer: make([]*int, 4),
+	}
+
+	for i := range 4 {
+		ring.Enqueue(&i)
+	}
+
+	ring.Dequeue()
+	ring.DropUntil(3)
+	assert.Equal(t, []*int{nil, nil, nil}, ring.buffer[:3])
+	assert.NotNil(t, ring.buffer[3])
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"reflect"
+	"slices"
+	"unsafe"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// A column of part data containing pointers, stored as a typed slice so the garbage collector can see them.
+// Plain data parts are stored in byte buffers instead.
+type valueColumn struct {
+	values reflect.Value
+	raw    cbor.RawMessage // saved values, kept until the registry can tell their type
+}
+
+func newValueColumn(typ reflect.Type) *valueColumn {
+	return &valueColumn{
+		values: reflect.MakeSlice(reflect.SliceOf(typ), 0, 0),
+	}
+}
+
+// get a byte view of the memory of one value
+func (column *valueColumn) bytes(index int) []byte {
+	value := column.values.Index(index)
+	return unsafe.Slice((*byte)(value.Addr().UnsafePointer()), value.Type().Size())
+}
+
+func (column *valueColumn) pointer(index int) unsafe.Pointer {
+	return column.values.Index(index).Addr().UnsafePointer()
+}
+
+func (column *valueColumn) grow(n int) {
+	column.values = reflect.AppendSlice(column.values, reflect.MakeSlice(column.values.Type(), n, n))
+}
+
+// deletes index in the column and moves last value to fill it's place
+func (column *valueColumn) delete(index int) {
+	lastIndex := column.values.Len() - 1
+
+	if index != lastIndex {
+		column.values.Index(index).Set(column.values.Index(lastIndex))
+	}
+
+	// clear the last value so the references it held can be collected
+	column.values.Index(lastIndex).SetZero()
+	column.values = column.values.Slice(0, lastIndex)
+}
+
+func (column *valueColumn) MarshalCBOR() ([]byte, error) {
+	if !column.values.IsValid() {
+		return column.raw, nil
+	}
+
+	return cbor.Marshal(column.values.Interface())
+}
+
+func (column *valueColumn) UnmarshalCBOR(data []byte) error {
+	column.raw = slices.Clone(data)
+	return nil
+}
+
+// decode saved values into a slice of a type, matching struct fields by name
+func (column *valueColumn) decode(typ reflect.Type) error {
+	values := reflect.New(reflect.SliceOf(typ))
+
+	err := cbor.Unmarshal(column.raw, values.Interface())
+	if err != nil {
+		return err
+	}
+
+	column.values = values.Elem()
+	column.raw = nil
+	return nil
+}
+
+// Check if a type contains pointers the garbage collector must see, and can't be stored as raw bytes.
+func hasPointers(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.String, reflect.Slice,
+		reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+		return true
+	case reflect.Array:
+		return typ.Len() > 0 && hasPointers(typ.Elem())
+	case reflect.Struct:
+		for i := range typ.NumField() {
+			if hasPointers(typ.Field(i).Type) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Find a field that can't be saved by value in a type, as unexported fields are skipped when saving.
+// Returns the path to the field from the type.
+func unexportedField(typ reflect.Type, seen map[reflect.Type]bool) (path string, found bool) {
+	if seen[typ] {
+		return "", false
+	}
+	seen[typ] = true
+
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return unexportedField(typ.Elem(), seen)
+	case reflect.Map:
+		if path, found := unexportedField(typ.Key(), seen); found {
+			return path, true
+		}
+
+		return unexportedField(typ.Elem(), seen)
+	case reflect.Struct:
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+
+			// the fields of embedded structs are saved as if they were fields of the struct
+			if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+				return field.Name, true
+			}
+
+			if path, found := unexportedField(field.Type, seen); found {
+				return field.Name + "." + path, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,46 @@
+package storage
+
+// The archetypes reached from an archetype by adding or deleting one part.
+// Cached so repeated transitions skip building and hashing signatures.
+type archetypeEdges struct {
+	added   map[PartId]archetypeId
+	deleted map[PartId]archetypeId
+}
+
+// get the edges of an archetype, creating them if the archetype is new or the store was loaded
+func (store *Store) edgesOf(archetype archetypeId) *archetypeEdges {
+	for len(store.edges) < len(store.Archetypes) {
+		store.edges = append(store.edges, &archetypeEdges{
+			added:   map[PartId]archetypeId{},
+			deleted: map[PartId]archetypeId{},
+		})
+	}
+
+	return store.edges[archetype]
+}
+
+// find the archetype reached by adding a part to an archetype
+func (store *Store) archetypeWith(archetype archetypeId, partId PartId) archetypeId {
+	edges := store.edgesOf(archetype)
+
+	next, exists := edges.added[partId]
+	if !exists {
+		next = store.archetypeOf(store.Archetypes[archetype].Add(partId))
+		edges.added[partId] = next
+	}
+
+	return next
+}
+
+// find the archetype reached by deleting a part from an archetype
+func (store *Store) archetypeWithout(archetype archetypeId, partId PartId) archetypeId {
+	edges := store.edgesOf(archetype)
+
+	next, exists := edges.deleted[partId]
+	if !exists {
+		next = store.archetypeOf(store.Archetypes[archetype].Delete(partId))
+		edges.deleted[partId] = next
+	}
+
+	return next
+}
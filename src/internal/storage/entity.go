@@ -0,0 +1,70 @@
+package storage
+
+// An entity id packs the index of an entity slot into the low 32 bits and the generation of that slot into the high 32 bits.
+// Slots are recycled after deletion with an incremented generation, so stale ids never match a newer entity.
+type EntityId uint64
+
+func NewEntityId(index uint32, generation uint32) EntityId {
+	return EntityId(uint64(generation)<<32 | uint64(index))
+}
+
+func (entity EntityId) Index() uint32 {
+	return uint32(entity)
+}
+
+func (entity EntityId) Generation() uint32 {
+	return uint32(entity >> 32)
+}
+
+// allocate ids for new entities, reusing deleted slots before creating new ones
+func (store *Store) allocate(n int) []EntityId {
+	entities := make([]EntityId, n)
+
+	for i := range entities {
+		if len(store.FreeIndices) > 0 {
+			lastFree := len(store.FreeIndices) - 1
+			index := store.FreeIndices[lastFree]
+			store.FreeIndices = store.FreeIndices[:lastFree]
+
+			entities[i] = NewEntityId(index, store.Entries[index].Generation)
+			continue
+		}
+
+		index := uint32(len(store.Entries))
+		store.Entries = append(store.Entries, entry{ArchetypeId: noArchetype})
+		entities[i] = NewEntityId(index, 0)
+	}
+
+	return entities
+}
+
+// release the slot of a deleted entity so it can be reused by a later generation
+func (store *Store) free(entity EntityId) {
+	index := entity.Index()
+
+	store.Entries[index] = entry{
+		ArchetypeId: noArchetype,
+		Generation:  entity.Generation() + 1,
+	}
+	store.FreeIndices = append(store.FreeIndices, index)
+}
+
+// find the entry of an entity, failing if the entity was deleted or it's slot was reused
+func (store *Store) lookup(entity EntityId) (entry entry, exists bool) {
+	index := entity.Index()
+	if int(index) >= len(store.Entries) {
+		return entry, false
+	}
+
+	entry = store.Entries[index]
+	if entry.ArchetypeId == noArchetype || entry.Generation != entity.Generation() {
+		return entry, false
+	}
+
+	return entry, true
+}
+
+func (store *Store) Exists(entity EntityId) bool {
+	_, exists := store.lookup(entity)
+	return exists
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const benchmarkEntityCount = 1_000_000
+
+func TestEntityId(t *testing.T) {
+	entity := NewEntityId(73, 4)
+
+	assert.Equal(t, uint32(73), entity.Index())
+	assert.Equal(t, uint32(4), entity.Generation())
+	assert.Equal(t, EntityId(4<<32|73), entity)
+}
+
+func TestStorageLookup(t *testing.T) {
+	storage := newTestStore(
+		map[EntityId]entry{
+			NewEntityId(1, 3): {ArchetypeId: 2, Index: 0},
+		},
+		map[archetypeId]*Page{}, 2)
+
+	got, exists := storage.lookup(NewEntityId(1, 3))
+	assert.True(t, exists)
+	assert.Equal(t, entry{ArchetypeId: 2, Index: 0, Generation: 3}, got)
+
+	_, exists = storage.lookup(NewEntityId(1, 2)) // stale generation
+	assert.False(t, exists)
+
+	_, exists = storage.lookup(NewEntityId(0, 0)) // free slot
+	assert.False(t, exists)
+
+	_, exists = storage.lookup(NewEntityId(2, 0)) // out of range
+	assert.False(t, exists)
+}
+
+// The dense index lookup used by the store.
+func BenchmarkEntriesDense(b *testing.B) {
+	store, entities := newBenchmarkStore()
+
+	b.ResetTimer()
+	for i := range b.N {
+		store.lookup(entities[i%len(entities)])
+	}
+}
+
+// The map index the store used before entities were indexed densely, for comparison.
+func BenchmarkEntriesMap(b *testing.B) {
+	store, entities := newBenchmarkStore()
+
+	entries := make(map[EntityId]entry, len(entities))
+	for _, entity := range entities {
+		entries[entity], _ = store.lookup(entity)
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		_ = entries[entities[i%len(entities)]]
+	}
+}
+
+func BenchmarkStoreGetComponent(b *testing.B) {
+	store, entities := newBenchmarkStore()
+
+	b.ResetTimer()
+	for i := range b.N {
+		store.GetComponent(entities[i%len(entities)], PartId(integerComponent))
+	}
+}
+
+func BenchmarkStoreHasPart(b *testing.B) {
+	store, entities := newBenchmarkStore()
+
+	b.ResetTimer()
+	for i := range b.N {
+		store.HasPart(entities[i%len(entities)], shortComponent)
+	}
+}
+
+func BenchmarkStoreMove(b *testing.B) {
+	store, entities := newBenchmarkStore()
+	shortArchetype := store.NewArchetype([]Part{shortComponent})
+	bothArchetype := store.NewArchetype([]Part{shortComponent, integerComponent})
+
+	b.ResetTimer()
+	for i := range b.N {
+		entity := entities[i%len(entities)]
+		if i/len(entities)%2 == 0 {
+			store.move(entity, shortArchetype, store.Tick)
+		} else {
+			store.move(entity, bothArchetype, store.Tick)
+		}
+	}
+}
+
+// create a store with a million entities, returning them in a random order for random access
+func newBenchmarkStore() (*Store, []EntityId) {
+	store := NewStore(newTestRegistry())
+	archetype := store.NewArchetype([]Part{shortComponent, integerComponent})
+	entities := store.Grow(archetype, benchmarkEntityCount, store.Tick)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	rng.Shuffle(len(entities), func(i, j int) {
+		entities[i], entities[j] = entities[j], entities[i]
+	})
+
+	return store, entities
+}
@@ -1,38 +1,56 @@
 package storage
 
 import (
-	"iter"
 	"slices"
+	"unsafe"
 )
 
+// a zero sized value for empty columns to point at
+var emptyColumn struct{}
+
 type Page struct {
 	_           struct{} `cbor:",toarray"`
 	PartBuffers map[PartId][]byte
+	PartValues  map[PartId]*valueColumn
 	Entities    []EntityId
 	DirtySize   int
 	Size        int
+	Ticks       map[PartId][]ChangeTicks
+	registry    *Registry
 }
 
-func (page *Page) GetComponentIter(componentId PartId) iter.Seq[[]byte] {
-	typ, success := partBufferTypes[componentId]
+// The ticks a component of an entity was added and last changed at.
+type ChangeTicks struct {
+	_       struct{} `cbor:",toarray"`
+	Added   uint64
+	Changed uint64
+}
 
-	if !success {
-		// empty iterator if this page doesn't have that component
-		return func(yield func([]byte) bool) {}
+// Get a pointer to the first value in the column of a part, to view the column as a typed slice of the page size.
+func (page *Page) Column(partId PartId) (pointer unsafe.Pointer, exists bool) {
+	if column, exists := page.PartValues[partId]; exists {
+		return column.values.UnsafePointer(), true
 	}
 
-	buffer := page.PartBuffers[componentId]
-	typeSize := int(typ.Size())
+	buffer, exists := page.PartBuffers[partId]
+	if !exists {
+		return nil, false
+	}
 
-	return func(yield func([]byte) bool) {
-		for i := range page.Size {
-			componentOffset := i * typeSize
-			componentBytes := buffer[componentOffset : componentOffset+typeSize]
-			if !yield(componentBytes) {
-				return
-			}
-		}
+	if len(buffer) == 0 {
+		// parts without data, or an empty page
+		return unsafe.Pointer(&emptyColumn), true
 	}
+
+	return unsafe.Pointer(&buffer[0]), true
+}
+
+// Get the change ticks of the rows in the column of a part.
+// Tags are never changed, so only their added ticks are meaningful.
+// Fails if the page doesn't have the part.
+func (page *Page) ChangeTicks(partId PartId) (ticks []ChangeTicks, exists bool) {
+	ticks, exists = page.Ticks[partId]
+	return ticks, exists
 }
 
 // deletes index in the page and moves last element to fill it's place
@@ -48,8 +66,7 @@ func (page *Page) delete(index int) {
 	// last we must move all the components of the last entity
 	// now that the last element has been written over the removed element, we can shorten
 	for componentId, buffer := range page.PartBuffers {
-		typ := partBufferTypes[componentId]
-		typeSize := int(typ.Size())
+		typeSize := page.registry.partSize(componentId)
 
 		indexOffset := index * typeSize
 		lastIndexOffset := lastIndex * typeSize
@@ -61,14 +78,25 @@ func (page *Page) delete(index int) {
 		page.PartBuffers[componentId] = buffer[0 : len(buffer)-typeSize]
 	}
 
+	for _, column := range page.PartValues {
+		column.delete(index)
+	}
+
+	for partId, ticks := range page.Ticks {
+		ticks[index] = ticks[lastIndex]
+		page.Ticks[partId] = ticks[:lastIndex]
+	}
+
 	page.Size--
 }
 
-func (page *Page) grow(n int, firstEntity EntityId) (firstIndex int) {
+// grows the page for new entities, marking their parts as added at a tick
+func (page *Page) grow(entities []EntityId, tick uint64) (firstIndex int) {
+	n := len(entities)
+
 	// grow component buffers
 	for componentId, buffer := range page.PartBuffers {
-		typ := partBufferTypes[componentId]
-		typeSize := int(typ.Size())
+		typeSize := page.registry.partSize(componentId)
 		growSize := typeSize * n
 		newLen := len(buffer) + growSize
 
@@ -84,14 +112,44 @@ func (page *Page) grow(n int, firstEntity EntityId) (firstIndex int) {
 		page.PartBuffers[componentId] = buffer
 	}
 
+	for _, column := range page.PartValues {
+		column.grow(n)
+	}
+
+	for partId, ticks := range page.Ticks {
+		for range n {
+			ticks = append(ticks, ChangeTicks{Added: tick, Changed: tick})
+		}
+		page.Ticks[partId] = ticks
+	}
+
 	firstIndex = len(page.Entities)
 
 	// grow entity list
-	for i := range n {
-		page.Entities = append(page.Entities, firstEntity+EntityId(i))
-	}
+	page.Entities = append(page.Entities, entities...)
 
 	page.Size += n
 	page.DirtySize = max(page.DirtySize, page.Size)
 	return
 }
+
+// deletes every entity in the page, keeping the capacity of it's buffers
+func (page *Page) clear() {
+	page.Entities = page.Entities[:0]
+
+	for componentId, buffer := range page.PartBuffers {
+		page.PartBuffers[componentId] = buffer[:0]
+	}
+
+	for _, column := range page.PartValues {
+		// clear the values so the references they held can be collected
+		column.values.Clear()
+		column.values = column.values.Slice(0, 0)
+	}
+
+	for partId, ticks := range page.Ticks {
+		page.Ticks[partId] = ticks[:0]
+	}
+
+	page.Size = 0
+}
@@ -4,29 +4,34 @@ import (
 	"encoding/binary"
 	"reflect"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestPageGetComponentIter(t *testing.T) {
+func TestPageColumn(t *testing.T) {
 	Page := newTestPage(
 		[]EntityId{54, 9, 32},
 		[]byte{0, 0, 4, 0, 25, 1},                  // []uint16{0, 4, 281}
 		[]byte{3, 0, 0, 0, 4, 1, 0, 0, 2, 0, 1, 0}) // []uint32{3, 260, 65538}
 
-	shorts := []uint16{}
-	for bytes := range Page.GetComponentIter(0) {
-		shorts = append(shorts, binary.LittleEndian.Uint16(bytes))
+	pointer, exists := Page.Column(0)
+	if assert.True(t, exists) {
+		assert.Equal(t, []uint16{0, 4, 281}, unsafe.Slice((*uint16)(pointer), Page.Size))
 	}
 
-	assert.ElementsMatch(t, shorts, []uint16{0, 4, 281})
+	pointer, exists = Page.Column(1)
+	if assert.True(t, exists) {
+		integers := unsafe.Slice((*uint32)(pointer), Page.Size)
+		assert.Equal(t, []uint32{3, 260, 65538}, integers)
 
-	integers := []uint32{}
-	for bytes := range Page.GetComponentIter(1) {
-		integers = append(integers, binary.LittleEndian.Uint32(bytes))
+		// the view writes into the page
+		integers[1] = 7
+		assert.Equal(t, []byte{7, 0, 0, 0}, Page.PartBuffers[1][4:8])
 	}
 
-	assert.ElementsMatch(t, integers, []uint32{3, 260, 65538})
+	_, exists = Page.Column(2)
+	assert.False(t, exists)
 }
 
 func TestPageDelete(t *testing.T) {
@@ -46,17 +51,12 @@ func TestPageGrow(t *testing.T) {
 		[]byte{3, 1},       // []uint16{259}
 		[]byte{6, 0, 0, 0}) // []uint32{6}
 
-	Page.grow(2, EntityId(25))
+	Page.grow([]EntityId{25, 26}, 1)
 
 	assert.ElementsMatch(t, []entityData{{12, 259, 6}, {25, 0, 0}, {26, 0, 0}}, readPage(Page))
 }
 
 func newTestPage(entityIds []EntityId, shortBuffer, integerBuffer []byte) *Page {
-	partBufferTypes = map[PartId]reflect.Type{
-		PartId(shortComponent):   reflect.TypeFor[uint16](),
-		PartId(integerComponent): reflect.TypeFor[uint32](),
-	}
-
 	return &Page{
 		PartBuffers: map[PartId][]byte{
 			PartId(shortComponent):   shortBuffer,
@@ -65,9 +65,18 @@ func newTestPage(entityIds []EntityId, shortBuffer, integerBuffer []byte) *Page
 		Entities:  entityIds,
 		Size:      len(entityIds),
 		DirtySize: len(entityIds),
+		registry:  newTestRegistry(),
 	}
 }
 
+func newTestRegistry() *Registry {
+	registry := NewRegistry()
+	registry.NewComponent("short", reflect.TypeFor[uint16]())   // shortComponent
+	registry.NewComponent("integer", reflect.TypeFor[uint32]()) // integerComponent
+
+	return registry
+}
+
 func readPage(Page *Page) []entityData {
 	entities := []entityData{}
 
@@ -79,3 +88,32 @@ func readPage(Page *Page) []entityData {
 
 	return entities
 }
+
+func TestPageValues(t *testing.T) {
+	Page := newTestPage([]EntityId{}, []byte{}, []byte{})
+	Page.PartValues = map[PartId]*valueColumn{
+		2: newValueColumn(reflect.TypeFor[string]()),
+	}
+
+	Page.grow([]EntityId{3, 8, 5}, 1)
+	values := Page.PartValues[2].values.Interface().([]string)
+	values[0], values[1], values[2] = "a", "b", "c"
+
+	Page.delete(0)
+	assert.Equal(t, []string{"c", "b"}, Page.PartValues[2].values.Interface())
+	assert.Equal(t, []string{"c", "b", ""}, values) // released value is cleared
+}
+
+func TestHasPointers(t *testing.T) {
+	assert.False(t, hasPointers(reflect.TypeFor[uint32]()))
+	assert.False(t, hasPointers(reflect.TypeFor[[4]float32]()))
+	assert.False(t, hasPointers(reflect.TypeFor[struct{ X, Y float32 }]()))
+
+	assert.True(t, hasPointers(reflect.TypeFor[string]()))
+	assert.True(t, hasPointers(reflect.TypeFor[*int]()))
+	assert.True(t, hasPointers(reflect.TypeFor[[2][]int]()))
+	assert.True(t, hasPointers(reflect.TypeFor[struct {
+		X     float32
+		Names map[string]int
+	}]()))
+}
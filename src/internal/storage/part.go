@@ -1,50 +1,35 @@
 package storage
 
-import (
-	"encoding/binary"
-	"hash/crc64"
-	"reflect"
-
-	"github.com/averagestardust/wecs/internal/common"
-)
-
 type PartId uint32
 type Part interface {
 	PartId() PartId
 }
 
-var partBufferTypes = map[PartId]reflect.Type{}
-
-func cmpPart(a Part, b Part) int {
-	return int(a.PartId()) - int(b.PartId())
-}
-
-func NewPartType(partId PartId, typ reflect.Type) {
-	partBufferTypes[partId] = typ
+func (partId PartId) PartId() PartId {
+	return partId
 }
 
-func (store *Store) DeletePart(entity EntityId, part Part) (success bool) {
-	entry, exists := store.Entries[entity]
+func (store *Store) DeletePart(entity EntityId, part Part, tick uint64) (success bool) {
+	entry, exists := store.lookup(entity)
 
 	if !exists {
 		return false
 	}
 
 	oldArchetypeId := entry.ArchetypeId
-	archetype := store.Archetypes[oldArchetypeId].Delete(part)
-	archetypeId := store.NewArchetype(archetype)
+	archetypeId := store.archetypeWithout(oldArchetypeId, part.PartId())
 
 	if oldArchetypeId == archetypeId {
 		// failed because entity never had that part
 		return false
 	}
 
-	store.move(entity, archetypeId)
+	store.move(entity, archetypeId, tick)
 	return true
 }
 
 func (store *Store) HasPart(entity EntityId, part Part) (has bool) {
-	entry, exists := store.Entries[entity]
+	entry, exists := store.lookup(entity)
 	if !exists {
 		return false
 	}
@@ -52,46 +37,71 @@ func (store *Store) HasPart(entity EntityId, part Part) (has bool) {
 	return store.Archetypes[entry.ArchetypeId].ContainsSingle(part)
 }
 
-func (store *Store) AddPart(entity EntityId, part Part) (success bool) {
-	entry, exists := store.Entries[entity]
+// Get the signature of the parts an entity has.
+func (store *Store) PartsOf(entity EntityId) (parts Signature, exists bool) {
+	entry, exists := store.lookup(entity)
+	if !exists {
+		return nil, false
+	}
+
+	return store.Archetypes[entry.ArchetypeId], true
+}
+
+func (store *Store) AddPart(entity EntityId, part Part, tick uint64) (success bool) {
+	entry, exists := store.lookup(entity)
 
 	if !exists {
 		return false
 	}
 
 	oldArchetypeId := entry.ArchetypeId
-	oldArchetype := store.Archetypes[oldArchetypeId]
-	archetypeId := store.NewArchetype(oldArchetype.Add(part))
+	archetypeId := store.archetypeWith(oldArchetypeId, part.PartId())
 
 	if oldArchetypeId == archetypeId {
 		// failed because entity already had that part
 		return false
 	}
 
-	store.move(entity, archetypeId)
+	store.move(entity, archetypeId, tick)
 	return true
 }
 
-func HashUsedParts(store *Store) uint64 {
-	hash := crc64.New(common.Crc64ISOTable)
-	uint32Bytes := make([]byte, 4)
+// Add and delete several parts from an entity, moving it to it's final archetype once.
+// Added parts and removals are marked with a tick.
+// Fails if the entity doesn't exist.
+func (store *Store) ChangeParts(entity EntityId, added []Part, deleted []Part, tick uint64) (success bool) {
+	entry, exists := store.lookup(entity)
+
+	if !exists {
+		return false
+	}
 
-	for part := range store.Parts {
-		componentId := part.PartId()
-		binary.LittleEndian.PutUint32(uint32Bytes, uint32(componentId))
-		hash.Write(uint32Bytes)
+	archetypeId := store.archetypeOf(store.Archetypes[entry.ArchetypeId].Change(added, deleted))
 
-		if int(componentId) >= len(partBufferTypes) {
-			continue
-		}
+	if entry.ArchetypeId != archetypeId {
+		store.move(entity, archetypeId, tick)
+	}
 
-		typ, exists := partBufferTypes[componentId]
-		if !exists {
-			continue
-		}
+	return true
+}
 
-		hash.Write([]byte(typ.Name()))
+// Add and delete several parts from every entity on a page, moving them all to their final archetype at once.
+// Added parts and removals are marked with a tick.
+// Returns the page and the range of rows the entities ended up in.
+func (store *Store) ChangePageParts(page *Page, added []Part, deleted []Part, tick uint64) (dst *Page, firstIndex int, count int) {
+	if page.Size == 0 {
+		return page, 0, 0
 	}
 
-	return hash.Sum64()
+	oldArchetypeId := store.Entries[page.Entities[0].Index()].ArchetypeId
+	archetypeId := store.archetypeOf(store.Archetypes[oldArchetypeId].Change(added, deleted))
+
+	if oldArchetypeId == archetypeId {
+		return page, 0, page.Size
+	}
+
+	count = page.Size
+	firstIndex = store.movePage(oldArchetypeId, archetypeId, tick)
+
+	return store.Pages[archetypeId], firstIndex, count
 }
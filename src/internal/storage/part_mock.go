@@ -1,5 +1,4 @@
 package storage
 
-type partMock uint32
-
-func (part partMock) PartId() PartId { return PartId(part) }
+// part ids are parts themselves, so tests can build signatures directly from mocks
+type partMock = PartId
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// The part types shared by a set of stores.
+// Stores sharing a registry agree on the ids and data types of their parts.
+type Registry struct {
+	partTypes     map[PartId]reflect.Type
+	partSchemas   map[PartId]PartSchema
+	partIds       map[string]PartId
+	migrations    map[PartId]map[uint32]Migration
+	nextComponent PartId
+	nextTag       PartId
+	recorded      map[PartId]struct{} // parts every store records removals of
+	deletions     bool                // whether every store records deletions
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		partTypes:     map[PartId]reflect.Type{},
+		partSchemas:   map[PartId]PartSchema{},
+		partIds:       map[string]PartId{},
+		migrations:    map[PartId]map[uint32]Migration{},
+		recorded:      map[PartId]struct{}{},
+		nextComponent: 0,
+		nextTag:       PartId(^uint32(0)),
+	}
+}
+
+// Register a part with data, component ids increment from zero.
+// Panics if the name is already used by another part.
+func (registry *Registry) NewComponent(name string, typ reflect.Type) PartId {
+	partId := registry.nextComponent
+	registry.register(partId, newPartSchema(name, typ))
+	registry.partTypes[partId] = typ
+	registry.nextComponent++
+
+	return partId
+}
+
+// Register a part without data, tag ids decrement from the maximum uint32 value.
+// Panics if the name is already used by another part.
+func (registry *Registry) NewTag(name string) PartId {
+	partId := registry.nextTag
+	registry.register(partId, PartSchema{Name: name})
+	registry.nextTag--
+
+	return partId
+}
+
+func (registry *Registry) register(partId PartId, schema PartSchema) {
+	if _, exists := registry.partIds[schema.Name]; exists {
+		panic(fmt.Sprintf("wecs: part name %q is already registered", schema.Name))
+	}
+
+	registry.partIds[schema.Name] = partId
+	registry.partSchemas[partId] = schema
+}
+
+// Get the data type of a part, tags have no data type.
+func (registry *Registry) PartType(partId PartId) (typ reflect.Type, exists bool) {
+	typ, exists = registry.partTypes[partId]
+	return
+}
+
+// Get the schema a part was registered with.
+func (registry *Registry) Schema(partId PartId) (schema PartSchema, exists bool) {
+	schema, exists = registry.partSchemas[partId]
+	return
+}
+
+// Find the id of a part by the name it was registered with.
+func (registry *Registry) Lookup(name string) (partId PartId, exists bool) {
+	partId, exists = registry.partIds[name]
+	return
+}
+
+// A conversion of saved part data from an old version of it's type.
+type Migration struct {
+	Schema  PartSchema
+	Type    reflect.Type
+	Convert func(old unsafe.Pointer, data unsafe.Pointer)
+}
+
+// Register a migration from an old version of a component, moving the component to the next version if needed.
+func (registry *Registry) AddMigration(partId PartId, version uint32, oldType reflect.Type, convert func(old unsafe.Pointer, data unsafe.Pointer)) {
+	schema := registry.partSchemas[partId]
+	schema.Version = max(schema.Version, version+1)
+	registry.partSchemas[partId] = schema
+
+	if registry.migrations[partId] == nil {
+		registry.migrations[partId] = map[uint32]Migration{}
+	}
+
+	oldSchema := newPartSchema(schema.Name, oldType)
+	oldSchema.Version = version
+	registry.migrations[partId][version] = Migration{Schema: oldSchema, Type: oldType, Convert: convert}
+}
+
+// Get the migration of a part from an old version.
+func (registry *Registry) Migration(partId PartId, version uint32) (migration Migration, exists bool) {
+	migration, exists = registry.migrations[partId][version]
+	return
+}
+
+// Record the removals of a part in every store using the registry, from the moment they're created.
+func (registry *Registry) RecordRemovals(partId PartId) {
+	registry.recorded[partId] = struct{}{}
+}
+
+// Record deleted entities in every store using the registry, from the moment they're created.
+func (registry *Registry) RecordDeletions() {
+	registry.deletions = true
+}
+
+func (registry *Registry) partSize(partId PartId) int {
+	return int(registry.partTypes[partId].Size())
+}
+
+func (registry *Registry) hasPointers(partId PartId) bool {
+	return registry.partSchemas[partId].Pointers
+}
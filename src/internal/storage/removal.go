@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// a log of the entities a part was removed from, along with the last data of the part
+type removalLog struct {
+	entities []EntityId
+	ticks    []uint64
+	buffer   []byte       // last data of plain data parts
+	values   *valueColumn // last data of parts with pointers
+	size     int
+}
+
+func (store *Store) newRemovalLog(partId PartId) *removalLog {
+	log := &removalLog{}
+
+	typ, exists := store.registry.PartType(partId)
+	if !exists {
+		// tags only record entities
+		return log
+	}
+
+	if store.registry.hasPointers(partId) {
+		log.values = newValueColumn(typ)
+	}
+	log.size = int(typ.Size())
+
+	return log
+}
+
+// Get the log of entities a part was removed from, with the ticks they were removed at and a pointer to view their last data as a typed slice.
+// Removals are recorded from when the store is created if the registry records the part, otherwise from the first time the log is read.
+// Safe to use from systems running at the same time.
+func (store *Store) Removals(partId PartId) (entities []EntityId, ticks []uint64, pointer unsafe.Pointer) {
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	log := store.removalLog(partId, true)
+	return log.entities, log.ticks, log.pointer()
+}
+
+// Get the log of deleted entities, with the ticks they were deleted at.
+// Deletions are recorded from when the store is created if the registry records them, otherwise from the first time the log is read.
+// Safe to use from systems running at the same time.
+func (store *Store) Deletions() (entities []EntityId, ticks []uint64) {
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	if store.deletions == nil {
+		store.deletions = &removalLog{}
+	}
+
+	return store.deletions.entities, store.deletions.ticks
+}
+
+// get the removal log of a part, creating it if it's read or the registry records the part, otherwise nil
+func (store *Store) removalLog(partId PartId, read bool) *removalLog {
+	if log, exists := store.removals[partId]; exists {
+		return log
+	}
+
+	if _, recorded := store.registry.recorded[partId]; !read && !recorded {
+		return nil
+	}
+
+	if store.removals == nil {
+		store.removals = map[PartId]*removalLog{}
+	}
+
+	log := store.newRemovalLog(partId)
+	store.removals[partId] = log
+	return log
+}
+
+// Forget removals and deletions from before a tick.
+func (store *Store) PruneRemovals(before uint64) {
+	for _, log := range store.removals {
+		log.prune(before)
+	}
+
+	if store.deletions != nil {
+		store.deletions.prune(before)
+	}
+}
+
+func (log *removalLog) pointer() unsafe.Pointer {
+	if log.values != nil {
+		return log.values.values.UnsafePointer()
+	}
+
+	if len(log.buffer) == 0 {
+		return unsafe.Pointer(&emptyColumn)
+	}
+
+	return unsafe.Pointer(&log.buffer[0])
+}
+
+// record the removal of the part in a row of a page
+func (log *removalLog) record(page *Page, partId PartId, index int, tick uint64) {
+	log.entities = append(log.entities, page.Entities[index])
+	log.ticks = append(log.ticks, tick)
+
+	if log.values != nil {
+		log.values.values = reflect.Append(log.values.values, page.PartValues[partId].values.Index(index))
+	} else if buffer, exists := page.PartBuffers[partId]; exists {
+		log.buffer = append(log.buffer, buffer[index*log.size:(index+1)*log.size]...)
+	}
+}
+
+// drop the entries from before a tick, entries are in tick order
+func (log *removalLog) prune(before uint64) {
+	n := 0
+	for n < len(log.ticks) && log.ticks[n] < before {
+		n++
+	}
+
+	if n == 0 {
+		return
+	}
+
+	// copy to the front, so the logs don't grow forever
+	remaining := len(log.ticks) - n
+	copy(log.entities, log.entities[n:])
+	log.entities = log.entities[:remaining]
+	copy(log.ticks, log.ticks[n:])
+	log.ticks = log.ticks[:remaining]
+
+	if log.values != nil {
+		values := log.values.values
+		reflect.Copy(values, values.Slice(n, values.Len()))
+		// clear the values so the references they held can be collected
+		values.Slice(remaining, values.Len()).Clear()
+		log.values.values = values.Slice(0, remaining)
+	} else {
+		copy(log.buffer, log.buffer[n*log.size:])
+		log.buffer = log.buffer[:remaining*log.size]
+	}
+}
+
+// record the parts removed from an entity moving between archetypes, or from a deleted entity when dst is nil
+func (store *Store) recordRemovals(src Signature, dst Signature, page *Page, index int, tick uint64) {
+	if store.removals == nil && len(store.registry.recorded) == 0 {
+		return
+	}
+
+	for _, partId := range src {
+		if dst.ContainsSingle(partId) {
+			continue
+		}
+
+		if log := store.removalLog(partId, false); log != nil {
+			log.record(page, partId, index, tick)
+		}
+	}
+}
+
+// record the deletion of an entity
+func (store *Store) recordDeletion(entity EntityId, tick uint64) {
+	if store.deletions == nil {
+		if !store.registry.deletions {
+			return
+		}
+
+		store.deletions = &removalLog{}
+	}
+
+	store.deletions.entities = append(store.deletions.entities, entity)
+	store.deletions.ticks = append(store.deletions.ticks, tick)
+}
@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"unsafe"
+)
+
+// The stable name, version and memory layout of a part, saved with a store to match parts on load.
+// Tags have an invalid kind and no size.
+// Struct parts have the layout of their fields, and array parts the layout of their element.
+// Parts with pointers are saved by value rather than by memory layout.
+type PartSchema struct {
+	_        struct{} `cbor:",toarray"`
+	Name     string
+	Version  uint32
+	Kind     reflect.Kind
+	Size     int
+	Pointers bool
+	Fields   []FieldSchema
+}
+
+// The layout of one field of a struct, or the element of an array.
+// Nested structs and arrays have the layout of their own fields or element, so changing a nested kind changes the layout.
+type FieldSchema struct {
+	_      struct{} `cbor:",toarray"`
+	Name   string
+	Kind   reflect.Kind
+	Offset int
+	Size   int
+	Fields []FieldSchema
+}
+
+func newPartSchema(name string, typ reflect.Type) PartSchema {
+	return PartSchema{
+		Name:     name,
+		Kind:     typ.Kind(),
+		Size:     int(typ.Size()),
+		Pointers: hasPointers(typ),
+		Fields:   fieldsOf(typ),
+	}
+}
+
+// Get the layout of the fields of a struct, or the element of an array as a single unnamed field.
+// Other types have no fields, including pointers and slices which are saved by value.
+func fieldsOf(typ reflect.Type) (fields []FieldSchema) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			fields = append(fields, FieldSchema{
+				Name:   field.Name,
+				Kind:   field.Type.Kind(),
+				Offset: int(field.Offset),
+				Size:   int(field.Type.Size()),
+				Fields: fieldsOf(field.Type),
+			})
+		}
+	case reflect.Array:
+		elem := typ.Elem()
+		fields = []FieldSchema{{
+			Kind:   elem.Kind(),
+			Size:   int(elem.Size()),
+			Fields: fieldsOf(elem),
+		}}
+	}
+
+	return fields
+}
+
+// Check if two schemas have the same name and layout, ignoring their versions.
+func (schema PartSchema) EqualTo(other PartSchema) bool {
+	return schema.Name == other.Name && schema.SameLayout(other)
+}
+
+func (schema PartSchema) SameLayout(other PartSchema) bool {
+	return schema.Kind == other.Kind &&
+		schema.Size == other.Size &&
+		schema.Pointers == other.Pointers &&
+		slices.EqualFunc(schema.Fields, other.Fields, FieldSchema.equalTo)
+}
+
+// Create a conversion from an old layout of a struct part, copying the fields with the same name.
+// New fields are left empty and removed fields are dropped.
+// Fails if the parts aren't structs, or a field with the same name changed layout.
+func (schema PartSchema) CopyFrom(old PartSchema) (convert func(old unsafe.Pointer, data unsafe.Pointer), compatible bool) {
+	if schema.Kind != reflect.Struct || old.Kind != reflect.Struct {
+		return nil, false
+	}
+
+	type span struct{ from, to, size int }
+	spans := []span{}
+
+	for _, field := range schema.Fields {
+		i := slices.IndexFunc(old.Fields, func(oldField FieldSchema) bool {
+			return oldField.Name == field.Name
+		})
+		if i < 0 {
+			continue
+		}
+
+		oldField := old.Fields[i]
+		if !oldField.sameLayout(field) {
+			return nil, false
+		}
+
+		spans = append(spans, span{oldField.Offset, field.Offset, field.Size})
+	}
+
+	return func(old unsafe.Pointer, data unsafe.Pointer) {
+		for _, span := range spans {
+			copy(
+				unsafe.Slice((*byte)(unsafe.Add(data, span.to)), span.size),
+				unsafe.Slice((*byte)(unsafe.Add(old, span.from)), span.size))
+		}
+	}, true
+}
+
+func (field FieldSchema) equalTo(other FieldSchema) bool {
+	return field.Name == other.Name &&
+		field.Offset == other.Offset &&
+		field.sameLayout(other)
+}
+
+// check if two fields have the same kind, size and nested layout, wherever they are
+func (field FieldSchema) sameLayout(other FieldSchema) bool {
+	return field.Kind == other.Kind &&
+		field.Size == other.Size &&
+		slices.EqualFunc(field.Fields, other.Fields, FieldSchema.equalTo)
+}
+
+// Get the schemas of every part used by the store.
+func (store *Store) Schemas() map[PartId]PartSchema {
+	schemas := map[PartId]PartSchema{}
+
+	for partId := range store.Parts {
+		schemas[partId], _ = store.registry.Schema(partId)
+	}
+
+	return schemas
+}
+
+// Change the ids of parts in the store, such as after loading a store saved with a different registry.
+// Every part used by the store must have a new id.
+func (store *Store) Remap(partIds map[PartId]PartId) {
+	store.ArchetypeMap = map[uint64][]archetypeId{}
+	store.edges = nil
+	for i, archetype := range store.Archetypes {
+		remapped := make([]PartId, len(archetype))
+		for j, partId := range archetype {
+			remapped[j] = partIds[partId]
+		}
+
+		store.Archetypes[i] = newSignatureFromIds(remapped)
+		hash := store.hashOf(store.Archetypes[i])
+		store.ArchetypeMap[hash] = append(store.ArchetypeMap[hash], archetypeId(i))
+	}
+
+	parts := map[PartId]struct{}{}
+	for partId := range store.Parts {
+		parts[partIds[partId]] = struct{}{}
+	}
+	store.Parts = parts
+
+	for _, page := range store.Pages {
+		if page == nil {
+			continue
+		}
+
+		buffers := map[PartId][]byte{}
+		for partId, buffer := range page.PartBuffers {
+			buffers[partIds[partId]] = buffer
+		}
+		page.PartBuffers = buffers
+
+		columns := map[PartId]*valueColumn{}
+		for partId, column := range page.PartValues {
+			columns[partIds[partId]] = column
+		}
+		page.PartValues = columns
+
+		ticks := map[PartId][]ChangeTicks{}
+		for partId, partTicks := range page.Ticks {
+			ticks[partIds[partId]] = partTicks
+		}
+		page.Ticks = ticks
+	}
+}
+
+// Rewrite the data of a part in every page from an old version to the type in the registry.
+// The old type is needed to decode saved parts with pointers, and may be nil for plain data.
+func (store *Store) Convert(partId PartId, oldSize int, oldType reflect.Type, convert func(old unsafe.Pointer, data unsafe.Pointer)) error {
+	typ, _ := store.registry.PartType(partId)
+	size := store.registry.partSize(partId)
+
+	for _, page := range store.Pages {
+		if page == nil {
+			continue
+		}
+
+		var oldPointer func(index int) unsafe.Pointer
+
+		if oldBuffer, exists := page.PartBuffers[partId]; exists {
+			oldPointer = func(index int) unsafe.Pointer {
+				return unsafe.Pointer(unsafe.SliceData(oldBuffer[index*oldSize:]))
+			}
+		} else if oldColumn, exists := page.PartValues[partId]; exists {
+			if oldType == nil {
+				return fmt.Errorf("%q: can't convert saved values without their old type", store.registry.partSchemas[partId].Name)
+			}
+
+			if err := oldColumn.decode(oldType); err != nil {
+				return fmt.Errorf("%q: %w", store.registry.partSchemas[partId].Name, err)
+			}
+
+			oldPointer = oldColumn.pointer
+		} else {
+			continue
+		}
+
+		delete(page.PartBuffers, partId)
+		delete(page.PartValues, partId)
+
+		if store.registry.hasPointers(partId) {
+			column := newValueColumn(typ)
+			column.grow(page.Size)
+
+			for i := range page.Size {
+				convert(oldPointer(i), column.pointer(i))
+			}
+
+			page.PartValues[partId] = column
+		} else {
+			buffer := make([]byte, page.Size*size)
+
+			for i := range page.Size {
+				convert(oldPointer(i), unsafe.Pointer(unsafe.SliceData(buffer[i*size:])))
+			}
+
+			page.PartBuffers[partId] = buffer
+		}
+	}
+
+	return nil
+}
+
+// Check that every part with pointers used by the store can be saved by value.
+// Fails on parts with unexported fields, which would be lost.
+func (store *Store) CheckValues() error {
+	for partId := range store.Parts {
+		if !store.registry.hasPointers(partId) {
+			continue
+		}
+
+		typ, _ := store.registry.PartType(partId)
+		if field, found := unexportedField(typ, map[reflect.Type]bool{}); found {
+			return fmt.Errorf("%q: unexported field %s can't be saved", store.registry.partSchemas[partId].Name, field)
+		}
+	}
+
+	return nil
+}
+
+// Decode the saved values of parts with pointers into their registered types, matching struct fields by name.
+// Should be used after the store is remapped and converted.
+func (store *Store) DecodeValues() error {
+	for _, page := range store.Pages {
+		if page == nil {
+			continue
+		}
+
+		for partId, column := range page.PartValues {
+			if column.raw == nil {
+				continue
+			}
+
+			typ, exists := store.registry.PartType(partId)
+			if !exists {
+				return fmt.Errorf("%q: can't decode saved values into a part without data", store.registry.partSchemas[partId].Name)
+			}
+
+			if err := column.decode(typ); err != nil {
+				return fmt.Errorf("%q: %w", store.registry.partSchemas[partId].Name, err)
+			}
+
+			if store.registry.hasPointers(partId) {
+				continue
+			}
+
+			// plain data that was saved with pointers is moved back into a byte buffer
+			buffer := make([]byte, 0, page.Size*int(typ.Size()))
+			for i := range page.Size {
+				buffer = append(buffer, column.bytes(i)...)
+			}
+
+			delete(page.PartValues, partId)
+			page.PartBuffers[partId] = buffer
+		}
+	}
+
+	return nil
+}
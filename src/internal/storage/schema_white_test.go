@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaEqualTo(t *testing.T) {
+	type vector struct {
+		X float32
+		Y float32
+	}
+	type swapped struct {
+		Y float32
+		X float32
+	}
+
+	schema := newPartSchema("vector", reflect.TypeFor[vector]())
+	assert.Equal(t, PartSchema{
+		Name: "vector",
+		Kind: reflect.Struct,
+		Size: 8,
+		Fields: []FieldSchema{
+			{Name: "X", Kind: reflect.Float32, Offset: 0, Size: 4},
+			{Name: "Y", Kind: reflect.Float32, Offset: 4, Size: 4},
+		},
+	}, schema)
+
+	assert.True(t, schema.EqualTo(newPartSchema("vector", reflect.TypeFor[vector]())))
+	assert.False(t, schema.EqualTo(newPartSchema("position", reflect.TypeFor[vector]())))
+	assert.False(t, schema.EqualTo(newPartSchema("vector", reflect.TypeFor[swapped]())))
+	assert.False(t, schema.EqualTo(newPartSchema("vector", reflect.TypeFor[[2]float32]())))
+}
+
+func TestSchemaNested(t *testing.T) {
+	type inner struct {
+		A uint16
+		B uint16
+	}
+	type nested struct {
+		V [2]inner
+	}
+
+	schema := newPartSchema("nested", reflect.TypeFor[nested]())
+	assert.Equal(t, PartSchema{
+		Name: "nested",
+		Kind: reflect.Struct,
+		Size: 8,
+		Fields: []FieldSchema{
+			{Name: "V", Kind: reflect.Array, Offset: 0, Size: 8, Fields: []FieldSchema{
+				{Kind: reflect.Struct, Size: 4, Fields: []FieldSchema{
+					{Name: "A", Kind: reflect.Uint16, Offset: 0, Size: 2},
+					{Name: "B", Kind: reflect.Uint16, Offset: 2, Size: 2},
+				}},
+			}},
+		},
+	}, schema)
+
+	// nested kinds are part of the layout, even with the same sizes
+	assert.False(t, newPartSchema("vector", reflect.TypeFor[struct{ V [2]float32 }]()).
+		EqualTo(newPartSchema("vector", reflect.TypeFor[struct{ V [2]int32 }]())))
+	assert.False(t, newPartSchema("vector", reflect.TypeFor[[2]float32]()).
+		EqualTo(newPartSchema("vector", reflect.TypeFor[[2]int32]())))
+	assert.False(t, schema.EqualTo(newPartSchema("nested", reflect.TypeFor[struct{ V [2]uint32 }]())))
+
+	// fields with changed nested layouts aren't copied
+	_, compatible := schema.CopyFrom(newPartSchema("nested", reflect.TypeFor[struct{ V [4]int16 }]()))
+	assert.False(t, compatible)
+
+	_, compatible = schema.CopyFrom(newPartSchema("nested", reflect.TypeFor[struct {
+		W uint32
+		V [2]inner
+	}]()))
+	assert.True(t, compatible)
+}
+
+func TestStorageRemap(t *testing.T) {
+	storage := newTestStore(
+		map[EntityId]entry{
+			0: {ArchetypeId: 0, Index: 0},
+		},
+		map[archetypeId]*Page{
+			0: {
+				PartBuffers: map[PartId][]byte{
+					PartId(shortComponent): {9, 0},
+				},
+				Entities:  []EntityId{0},
+				Size:      1,
+				DirtySize: 1,
+			},
+		}, 1)
+
+	tag1 := partMock(^uint32(1))
+	tag3 := partMock(^uint32(3))
+
+	// swap the short and integer components, and move the tags
+	storage.Remap(map[PartId]PartId{
+		shortComponent:   integerComponent,
+		integerComponent: shortComponent,
+		tag1:             tag3,
+		tag3:             tag1,
+	})
+
+	assert.Equal(t, []Signature{
+		{integerComponent, tag3},
+		{tag1},
+		{shortComponent, integerComponent},
+	}, storage.Archetypes)
+
+	assert.Equal(t, map[PartId]struct{}{shortComponent: {}, integerComponent: {}}, storage.Parts)
+	assert.Equal(t, map[PartId][]byte{integerComponent: {9, 0}}, storage.Pages[0].PartBuffers)
+
+	// remapped signatures still find their archetypes
+	assert.Equal(t, archetypeId(0), storage.NewArchetype([]Part{tag3, integerComponent}))
+	assert.Equal(t, archetypeId(1), storage.NewArchetype([]Part{tag1}))
+}
+
+func TestSchemaCopyFrom(t *testing.T) {
+	type old struct {
+		A uint16
+		B uint16
+		C uint32
+	}
+	type added struct {
+		C uint32
+		D uint16
+		A uint16
+	}
+	type changed struct {
+		A uint32
+	}
+
+	schema := newPartSchema("part", reflect.TypeFor[added]())
+	convert, compatible := schema.CopyFrom(newPartSchema("part", reflect.TypeFor[old]()))
+	if assert.True(t, compatible) {
+		old := []byte{1, 0, 2, 0, 3, 0, 0, 0}
+		data := make([]byte, 8)
+		convert(unsafe.Pointer(&old[0]), unsafe.Pointer(&data[0]))
+		assert.Equal(t, []byte{3, 0, 0, 0, 0, 0, 1, 0}, data)
+	}
+
+	_, compatible = newPartSchema("part", reflect.TypeFor[changed]()).CopyFrom(newPartSchema("part", reflect.TypeFor[old]()))
+	assert.False(t, compatible)
+
+	_, compatible = newPartSchema("part", reflect.TypeFor[uint64]()).CopyFrom(newPartSchema("part", reflect.TypeFor[uint32]()))
+	assert.False(t, compatible)
+}
@@ -8,42 +8,68 @@ import (
 	"github.com/averagestardust/wecs/internal/common"
 )
 
-type Signature []Part
+// A sorted set of part ids, identifying an archetype.
+type Signature []PartId
 
 func NewSignature(parts []Part) Signature {
-	PartSet := map[Part]struct{}{}
-	for _, Part := range parts {
-		PartSet[Part] = struct{}{}
+	partIds := make([]PartId, len(parts))
+	for i, part := range parts {
+		partIds[i] = part.PartId()
 	}
 
-	uniqueParts := []Part{}
-	for Part := range PartSet {
-		uniqueParts = append(uniqueParts, Part)
+	return newSignatureFromIds(partIds)
+}
+
+func newSignatureFromIds(partIds []PartId) Signature {
+	PartSet := map[PartId]struct{}{}
+	for _, partId := range partIds {
+		PartSet[partId] = struct{}{}
+	}
+
+	uniqueParts := Signature{}
+	for partId := range PartSet {
+		uniqueParts = append(uniqueParts, partId)
 	}
 
 	// sort for quick comparison
-	slices.SortFunc(uniqueParts, cmpPart)
+	slices.Sort(uniqueParts)
 
 	return uniqueParts
 }
 
 func (signature Signature) Add(newPart Part) Signature {
 	parts := slices.Clone(signature)
-	parts = append(parts, newPart)
+	parts = append(parts, newPart.PartId())
 
-	return NewSignature(parts)
+	return newSignatureFromIds(parts)
 }
 
 func (signature Signature) Delete(removedPart Part) Signature {
-	parts := []Part{}
+	parts := []PartId{}
 
-	for _, Part := range signature {
-		if Part != removedPart {
-			parts = append(parts, Part)
+	for _, partId := range signature {
+		if partId != removedPart.PartId() {
+			parts = append(parts, partId)
 		}
 	}
 
-	return NewSignature(parts)
+	return newSignatureFromIds(parts)
+}
+
+// Add and delete several parts at once, parts both added and deleted are deleted.
+func (signature Signature) Change(added []Part, deleted []Part) Signature {
+	parts := slices.Clone(signature)
+	for _, part := range added {
+		parts = append(parts, part.PartId())
+	}
+
+	parts = slices.DeleteFunc(parts, func(partId PartId) bool {
+		return slices.ContainsFunc(deleted, func(part Part) bool {
+			return part.PartId() == partId
+		})
+	})
+
+	return newSignatureFromIds(parts)
 }
 
 func (signature Signature) EqualTo(other Signature) bool {
@@ -65,7 +91,7 @@ func (signature Signature) ContainsAll(other Signature) bool {
 	i := 0
 	for _, component := range other {
 		// Signatures are in a sorted order, thus we only need to search areas after the previous match
-		n, found := slices.BinarySearchFunc(signature[i:], component, cmpPart)
+		n, found := slices.BinarySearch(signature[i:], component)
 		i += n + 1
 
 		if !found {
@@ -78,7 +104,7 @@ func (signature Signature) ContainsAll(other Signature) bool {
 
 func (signature Signature) ContainsAny(other Signature) bool {
 	for _, component := range other {
-		if _, found := slices.BinarySearchFunc(signature, component, cmpPart); found {
+		if _, found := slices.BinarySearch(signature, component); found {
 			return true
 		}
 	}
@@ -86,8 +112,8 @@ func (signature Signature) ContainsAny(other Signature) bool {
 	return false
 }
 
-func (signature Signature) ContainsSingle(Part Part) bool {
-	_, found := slices.BinarySearchFunc(signature, Part, cmpPart)
+func (signature Signature) ContainsSingle(part Part) bool {
+	_, found := slices.BinarySearch(signature, part.PartId())
 	return found
 }
 
@@ -95,8 +121,8 @@ func (signature Signature) hash() uint64 {
 	hash := crc64.New(common.Crc64ISOTable)
 	var bytes [4]byte
 
-	for _, Part := range signature {
-		binary.LittleEndian.PutUint32(bytes[:], uint32(Part.PartId()))
+	for _, partId := range signature {
+		binary.LittleEndian.PutUint32(bytes[:], uint32(partId))
 		hash.Write(bytes[:])
 	}
 
@@ -128,3 +128,58 @@ func TestSignatureHash(t *testing.T) {
 		crc64.Checksum([]byte{1, 0, 0, 0, 5, 0, 0, 0, 254, 255, 255, 255}, isoTable),
 		sig.hash())
 }
+
+func TestSignatureChange(t *testing.T) {
+	tag1 := partMock(^uint32(1))
+	tag4 := partMock(^uint32(4))
+	component0 := partMock(0)
+	component7 := partMock(7)
+
+	sig := Signature{component0, tag1}
+
+	assert.EqualValues(t, Signature{component0, component7, tag4}, sig.Change([]Part{tag4, component7}, []Part{tag1}))
+	assert.EqualValues(t, Signature{tag1}, sig.Change([]Part{component0}, []Part{component0}))
+	assert.EqualValues(t, Signature{component0, tag1}, sig.Change(nil, nil))
+}
+
+// A hash that collides for every signature with the same length.
+func lengthHash(signature Signature) uint64 {
+	return uint64(len(signature))
+}
+
+func TestSignatureHashCollision(t *testing.T) {
+	tag1 := partMock(^uint32(1))
+
+	// signatures of the same length share a bucket, others don't
+	storage := NewStore(newTestRegistry())
+	storage.hash = lengthHash
+
+	short := storage.NewArchetype([]Part{shortComponent, tag1})
+	integer := storage.NewArchetype([]Part{integerComponent, tag1})
+	both := storage.NewArchetype([]Part{shortComponent, integerComponent})
+	alone := storage.NewArchetype([]Part{shortComponent})
+
+	assert.Equal(t, map[uint64][]archetypeId{
+		2: {short, integer, both},
+		1: {alone},
+	}, storage.ArchetypeMap)
+	assert.Equal(t, integer, storage.NewArchetype([]Part{tag1, integerComponent}))
+
+	// colliding archetypes keep distinct pages
+	entities := []EntityId{}
+	for _, archetype := range []archetypeId{short, integer, both} {
+		entities = append(entities, storage.Grow(archetype, 1, storage.Tick)[0])
+	}
+	storage.GetComponent(entities[0], PartId(shortComponent))[0] = 1
+	storage.GetComponent(entities[1], PartId(integerComponent))[0] = 2
+	storage.GetComponent(entities[2], PartId(shortComponent))[0] = 3
+
+	assert.Len(t, storage.Pages, 3)
+	for i, archetype := range []archetypeId{short, integer, both} {
+		assert.Equal(t, []EntityId{entities[i]}, storage.Pages[archetype].Entities)
+	}
+	assert.Equal(t, []byte{1, 0}, storage.GetComponent(entities[0], PartId(shortComponent)))
+	assert.Equal(t, []byte{2, 0, 0, 0}, storage.GetComponent(entities[1], PartId(integerComponent)))
+	assert.Equal(t, []byte{3, 0}, storage.GetComponent(entities[2], PartId(shortComponent)))
+	assert.Nil(t, storage.GetComponent(entities[1], PartId(shortComponent)))
+}
@@ -1,89 +1,150 @@
 package storage
 
 import (
+	"reflect"
 	"sync"
 )
 
 type archetypeId uint32
-type EntityId uint64
 type ResourceId uint32
 
 type Store struct {
 	_            struct{} `cbor:",toarray"`
 	Archetypes   []Signature
-	ArchetypeMap map[uint64]archetypeId
-	Parts        map[Part]struct{}
-	Entries      map[EntityId]entry
-	Mutex        sync.Locker
-	NextEntity   EntityId
+	ArchetypeMap map[uint64][]archetypeId
+	Parts        map[PartId]struct{}
+	Entries      []entry
+	Mutex        sync.Locker `cbor:"-"`
+	FreeIndices  []uint32
 	NextTag      PartId
-	Pages        map[archetypeId]*Page
+	Pages        []*Page // indexed by archetype id, nil for archetypes without a page yet
 	Resources    map[string]any
+	Tick         uint64 // the current tick, that changes outside of systems are marked with
+	registry     *Registry
+	edges        []*archetypeEdges
+	hash         func(Signature) uint64 // hashes signatures for the archetype map, replaceable to test collisions
+	removals     map[PartId]*removalLog
+	deletions    *removalLog
 }
 
+// The location of an entity, addressed by the index of it's id.
+// Free slots keep their generation and have no archetype.
 type entry struct {
 	_           struct{} `cbor:",toarray"`
 	ArchetypeId archetypeId
 	Index       int
+	Generation  uint32
 }
 
-func NewStore() *Store {
+// The archetype id of free entity slots.
+const noArchetype = ^archetypeId(0)
+
+func NewStore(registry *Registry) *Store {
 	return &Store{
 		Archetypes:   nil,
-		ArchetypeMap: map[uint64]archetypeId{},
-		Parts:        map[Part]struct{}{},
-		Entries:      map[EntityId]entry{},
-		Pages:        map[archetypeId]*Page{},
+		ArchetypeMap: map[uint64][]archetypeId{},
+		Parts:        map[PartId]struct{}{},
+		Entries:      nil,
+		Pages:        nil,
 		Mutex:        &sync.Mutex{},
-		NextEntity:   0,
+		FreeIndices:  nil,
+		Tick:         1,
+		registry:     registry,
+		hash:         Signature.hash,
+	}
+}
+
+// Set the registry of a store and it's pages, after the store was deserialized.
+func (store *Store) SetRegistry(registry *Registry) {
+	store.registry = registry
+	if store.Mutex == nil {
+		store.Mutex = &sync.Mutex{}
+	}
+
+	for _, page := range store.Pages {
+		if page != nil {
+			page.registry = registry
+		}
 	}
 }
 
 func (store *Store) GetComponent(entity EntityId, componentId PartId) []byte {
-	entry, exists := store.Entries[entity]
+	entry, exists := store.lookup(entity)
 	if !exists {
 		return nil
 	}
 
 	page := store.Pages[entry.ArchetypeId]
+	if column, exists := page.PartValues[componentId]; exists {
+		return column.bytes(entry.Index)
+	}
+
 	buffer, exists := page.PartBuffers[componentId]
 	if !exists {
 		return nil
 	}
 
-	typ := partBufferTypes[componentId]
-	typeSize := int(typ.Size())
+	typeSize := store.registry.partSize(componentId)
 	componentOffset := entry.Index * typeSize
 
 	return buffer[componentOffset : componentOffset+typeSize]
 }
 
+// Find the page an entity is stored in, and it's index in the page.
+func (store *Store) Locate(entity EntityId) (page *Page, index int, exists bool) {
+	entry, exists := store.lookup(entity)
+	if !exists {
+		return nil, 0, false
+	}
+
+	return store.Pages[entry.ArchetypeId], entry.Index, true
+}
+
 func (store *Store) NewArchetype(parts []Part) archetypeId {
-	archetype := NewSignature(parts)
+	return store.archetypeOf(NewSignature(parts))
+}
+
+func (store *Store) hashOf(signature Signature) uint64 {
+	if store.hash == nil {
+		// deserialized stores use the default hash
+		return signature.hash()
+	}
+
+	return store.hash(signature)
+}
 
-	hash := archetype.hash()
+// find or create the archetype of a signature
+func (store *Store) archetypeOf(archetype Signature) archetypeId {
+	hash := store.hashOf(archetype)
 
-	id, exists := store.ArchetypeMap[hash]
-	if exists {
-		return id
+	// signatures are compared, as different signatures may share a hash
+	for _, id := range store.ArchetypeMap[hash] {
+		if store.Archetypes[id].EqualTo(archetype) {
+			return id
+		}
 	}
 
-	id = archetypeId(len(store.Archetypes))
+	id := archetypeId(len(store.Archetypes))
+
+	// record all parts in use
+	for _, partId := range archetype {
+		store.Parts[partId] = struct{}{}
+	}
 
 	store.Archetypes = append(store.Archetypes, archetype)
-	store.ArchetypeMap[hash] = id
+	store.ArchetypeMap[hash] = append(store.ArchetypeMap[hash], id)
 
 	return id
 }
 
-func (store *Store) move(entity EntityId, archetype archetypeId) {
-	entry := store.Entries[entity]
+func (store *Store) move(entity EntityId, archetype archetypeId, tick uint64) {
+	entry := store.Entries[entity.Index()]
 
 	src := store.Pages[entry.ArchetypeId]
 	dst := store.ensurePage(archetype)
 
 	srcIndex := entry.Index
-	dstIndex := dst.grow(1, entity) // grow destination page for copy
+	dstIndex := dst.grow([]EntityId{entity}, tick) // grow destination page for copy
 
 	for componentId, srcBuffer := range src.PartBuffers {
 		dstBuffer, exists := dst.PartBuffers[componentId]
@@ -93,8 +154,7 @@ func (store *Store) move(entity EntityId, archetype archetypeId) {
 			continue
 		}
 
-		typ := partBufferTypes[componentId]
-		typeSize := int(typ.Size())
+		typeSize := store.registry.partSize(componentId)
 
 		srcOffset := srcIndex * typeSize
 		dstOffset := dstIndex * typeSize
@@ -103,74 +163,166 @@ func (store *Store) move(entity EntityId, archetype archetypeId) {
 		copy(dstBuffer[dstOffset:dstOffset+typeSize], srcBuffer[srcOffset:srcOffset+typeSize])
 	}
 
-	// delete entity from entries and source page
-	store.Delete(entity)
+	for componentId, srcColumn := range src.PartValues {
+		dstColumn, exists := dst.PartValues[componentId]
+		if !exists {
+			continue
+		}
+
+		dstColumn.values.Index(dstIndex).Set(srcColumn.values.Index(srcIndex))
+	}
+
+	// parts the entity already had keep their ticks
+	for partId, srcTicks := range src.Ticks {
+		if dstTicks, exists := dst.Ticks[partId]; exists {
+			dstTicks[dstIndex] = srcTicks[srcIndex]
+		}
+	}
+
+	store.recordRemovals(store.Archetypes[entry.ArchetypeId], store.Archetypes[archetype], src, srcIndex, tick)
+
+	// delete entity from the source page, keeping it's id
+	store.detach(entry)
 
 	// update entry and save
 	entry.ArchetypeId = archetype
 	entry.Index = dstIndex
-	store.Entries[entity] = entry
+	store.Entries[entity.Index()] = entry
 }
 
-func (store *Store) Delete(entity EntityId) {
-	entry, exists := store.Entries[entity]
+// move every entity of one archetype to another, copying their parts in bulk
+func (store *Store) movePage(srcArchetype archetypeId, dstArchetype archetypeId, tick uint64) (firstIndex int) {
+	src := store.Pages[srcArchetype]
+	dst := store.ensurePage(dstArchetype)
+
+	n := src.Size
+	firstIndex = dst.grow(src.Entities, tick)
+
+	for componentId, srcBuffer := range src.PartBuffers {
+		dstBuffer, exists := dst.PartBuffers[componentId]
+
+		// skip copy if destination does not have this component
+		if !exists {
+			continue
+		}
+
+		typeSize := store.registry.partSize(componentId)
+		copy(dstBuffer[firstIndex*typeSize:], srcBuffer)
+	}
+
+	for componentId, srcColumn := range src.PartValues {
+		dstColumn, exists := dst.PartValues[componentId]
+		if !exists {
+			continue
+		}
+
+		reflect.Copy(dstColumn.values.Slice(firstIndex, firstIndex+n), srcColumn.values)
+	}
+
+	for partId, srcTicks := range src.Ticks {
+		if dstTicks, exists := dst.Ticks[partId]; exists {
+			copy(dstTicks[firstIndex:], srcTicks)
+		}
+	}
+
+	for i := range n {
+		store.recordRemovals(store.Archetypes[srcArchetype], store.Archetypes[dstArchetype], src, i, tick)
+	}
+
+	for i, entity := range dst.Entities[firstIndex:] {
+		store.Entries[entity.Index()].ArchetypeId = dstArchetype
+		store.Entries[entity.Index()].Index = firstIndex + i
+	}
+
+	src.clear()
+	return firstIndex
+}
+
+// Move to the next tick, returning the tick before.
+func (store *Store) AdvanceTick() (tick uint64) {
+	tick = store.Tick
+	store.Tick++
+	return tick
+}
+
+func (store *Store) Delete(entity EntityId, tick uint64) {
+	entry, exists := store.lookup(entity)
 	if !exists {
 		return
 	}
 
+	page := store.Pages[entry.ArchetypeId]
+	store.recordRemovals(store.Archetypes[entry.ArchetypeId], nil, page, entry.Index, tick)
+	store.recordDeletion(entity, tick)
+
+	store.detach(entry)
+
+	// delete the entry and recycle it's id
+	store.free(entity)
+}
+
+// remove the row of an entry from it's page, without deleting the entry
+func (store *Store) detach(entry entry) {
 	page := store.Pages[entry.ArchetypeId]
 
 	// move the entry of the last entity in the page to the deletion index
 	lastEntity := page.Entities[len(page.Entities)-1]
-	lastEntry := store.Entries[lastEntity]
-	lastEntry.Index = entry.Index
-	store.Entries[lastEntity] = lastEntry
-
-	// delete the entry
-	delete(store.Entries, entity)
+	store.Entries[lastEntity.Index()].Index = entry.Index
 
 	// delete in the page
 	page.delete(entry.Index)
 }
 
-func (store *Store) Grow(archetypeId archetypeId, n int) (firstEntity EntityId) {
-	firstNewIndex := store.ensurePage(archetypeId).grow(n, store.NextEntity)
+func (store *Store) Grow(archetypeId archetypeId, n int, tick uint64) (entities []EntityId) {
+	entities = store.allocate(n)
+	firstNewIndex := store.ensurePage(archetypeId).grow(entities, tick)
 
-	for i := range n {
-		newEntity := store.NextEntity + EntityId(i)
-		store.Entries[newEntity] = entry{ArchetypeId: archetypeId, Index: firstNewIndex + i}
+	for i, newEntity := range entities {
+		store.Entries[newEntity.Index()] = entry{
+			ArchetypeId: archetypeId,
+			Index:       firstNewIndex + i,
+			Generation:  newEntity.Generation(),
+		}
 	}
 
-	firstEntity = store.NextEntity
-	store.NextEntity += EntityId(n)
-
 	return
 }
 
 func (store *Store) ensurePage(archetypeId archetypeId) (newPage *Page) {
-	existingPage, exists := store.Pages[archetypeId]
-	if exists {
-		return existingPage
+	if int(archetypeId) < len(store.Pages) && store.Pages[archetypeId] != nil {
+		return store.Pages[archetypeId]
 	}
 
 	partBuffers := map[PartId][]byte{}
+	partValues := map[PartId]*valueColumn{}
+	ticks := map[PartId][]ChangeTicks{}
 	archetype := store.Archetypes[archetypeId]
-	for _, Part := range archetype {
-		// record all components in use
-		store.Parts[Part] = struct{}{}
+	for _, partId := range archetype {
+		// tags have ticks too, so filters can check when they were added
+		ticks[partId] = []ChangeTicks{}
 
-		_, exists := partBufferTypes[Part.PartId()]
+		typ, exists := store.registry.PartType(partId)
 		if !exists {
 			continue
 		}
 
-		partBuffers[Part.PartId()] = []byte{}
+		if store.registry.hasPointers(partId) {
+			partValues[partId] = newValueColumn(typ)
+		} else {
+			partBuffers[partId] = []byte{}
+		}
 	}
 
 	newPage = &Page{
 		PartBuffers: partBuffers,
+		PartValues:  partValues,
+		Ticks:       ticks,
+		registry:    store.registry,
 	}
 
+	if int(archetypeId) >= len(store.Pages) {
+		store.Pages = append(store.Pages, make([]*Page, int(archetypeId)+1-len(store.Pages))...)
+	}
 	store.Pages[archetypeId] = newPage
 	return
 }
@@ -5,6 +5,7 @@ import (
 	"hash/crc64"
 	"sync"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -75,7 +76,7 @@ func TestStorageNewArchetype(t *testing.T) {
 	component1 := partMock(1)
 	component5 := partMock(5)
 
-	storage := NewStore()
+	storage := NewStore(newTestRegistry())
 
 	assert.Equal(t, archetypeId(0), storage.NewArchetype([]Part{tag0}))
 	assert.Equal(t, archetypeId(1), storage.NewArchetype([]Part{tag2, component1}))
@@ -104,6 +105,7 @@ func TestStorageMove(t *testing.T) {
 				Entities:    []EntityId{0},
 				Size:        1,
 				DirtySize:   1,
+				registry:    newTestRegistry(),
 			},
 			2: newTestPage(
 				[]EntityId{2, 3},
@@ -112,16 +114,22 @@ func TestStorageMove(t *testing.T) {
 		}, 4)
 
 	// drop integer component from entity 2
-	storage.move(2, 0)
+	storage.move(2, 0, storage.Tick)
 
 	assert.EqualValues(t,
 		Page{
 			PartBuffers: map[PartId][]byte{
 				PartId(shortComponent): {34, 1},
 			},
-			Entities:  []EntityId{2},
-			Size:      1,
-			DirtySize: 1,
+			PartValues: map[PartId]*valueColumn{},
+			Entities:   []EntityId{2},
+			Size:       1,
+			DirtySize:  1,
+			Ticks: map[PartId][]ChangeTicks{
+				PartId(shortComponent): {{Added: 1, Changed: 1}},
+				PartId(^uint32(1)):     {{Added: 1, Changed: 1}}, // the tag was added by the move
+			},
+			registry: storage.registry,
 		},
 		*storage.Pages[0])
 
@@ -134,6 +142,7 @@ func TestStorageMove(t *testing.T) {
 			Entities:  []EntityId{3},
 			Size:      1,
 			DirtySize: 2,
+			registry:  storage.registry,
 		},
 		*storage.Pages[2])
 
@@ -143,10 +152,10 @@ func TestStorageMove(t *testing.T) {
 			2: {ArchetypeId: 0, Index: 0},
 			3: {ArchetypeId: 2, Index: 0},
 		},
-		storage.Entries)
+		readEntries(storage))
 
 	// add short and integer components to entity 0
-	storage.move(0, 2)
+	storage.move(0, 2, storage.Tick)
 
 	assert.EqualValues(t,
 		Page{
@@ -154,16 +163,18 @@ func TestStorageMove(t *testing.T) {
 			Entities:    []EntityId{},
 			Size:        0,
 			DirtySize:   1,
+			registry:    storage.registry,
 		},
 		*storage.Pages[1])
 
 	assert.ElementsMatch(t, []entityData{{0, 0, 0}, {3, 78, 7}}, readPage(storage.Pages[2]))
 
-	assert.Equal(t, storage.Entries[0].ArchetypeId, archetypeId(2))
-	assert.Equal(t, storage.Entries[2].ArchetypeId, archetypeId(0))
-	assert.Equal(t, storage.Entries[3].ArchetypeId, archetypeId(2))
+	entries := readEntries(storage)
+	assert.Equal(t, entries[0].ArchetypeId, archetypeId(2))
+	assert.Equal(t, entries[2].ArchetypeId, archetypeId(0))
+	assert.Equal(t, entries[3].ArchetypeId, archetypeId(2))
 
-	assert.NotEqual(t, storage.Entries[0].Index, storage.Entries[3].Index)
+	assert.NotEqual(t, entries[0].Index, entries[3].Index)
 }
 
 func TestStorageGrow(t *testing.T) {
@@ -178,16 +189,16 @@ func TestStorageGrow(t *testing.T) {
 				[]byte{0, 0, 0, 0}),
 		}, 7)
 
-	storage.Grow(0, 2)
+	storage.Grow(0, 2, storage.Tick)
 	assert.EqualValues(t,
 		map[EntityId]entry{
 			4: {ArchetypeId: 2, Index: 0},
 
 			7: {ArchetypeId: 0, Index: 0},
 			8: {ArchetypeId: 0, Index: 1}},
-		storage.Entries)
+		readEntries(storage))
 
-	storage.Grow(2, 3)
+	storage.Grow(2, 3, storage.Tick)
 	assert.EqualValues(t,
 		map[EntityId]entry{
 			4: {ArchetypeId: 2, Index: 0},
@@ -198,7 +209,36 @@ func TestStorageGrow(t *testing.T) {
 			9:  {ArchetypeId: 2, Index: 1},
 			10: {ArchetypeId: 2, Index: 2},
 			11: {ArchetypeId: 2, Index: 3}},
-		storage.Entries)
+		readEntries(storage))
+}
+
+func TestStorageRecycle(t *testing.T) {
+	storage := newTestStore(
+		map[EntityId]entry{
+			4: {ArchetypeId: 2, Index: 0},
+		},
+		map[archetypeId]*Page{
+			2: newTestPage(
+				[]EntityId{4},
+				[]byte{0, 0},
+				[]byte{0, 0, 0, 0}),
+		}, 5)
+
+	storage.Delete(4, storage.Tick)
+	assert.Equal(t, []uint32{4}, storage.FreeIndices)
+	assert.Equal(t, uint32(1), storage.Entries[4].Generation)
+	assert.False(t, storage.Exists(4))
+
+	// deleted slot is reused with a new generation
+	entities := storage.Grow(2, 2, storage.Tick)
+	assert.Equal(t, []EntityId{NewEntityId(4, 1), NewEntityId(5, 0)}, entities)
+	assert.Empty(t, storage.FreeIndices)
+
+	assert.EqualValues(t,
+		map[EntityId]entry{
+			NewEntityId(4, 1): {ArchetypeId: 2, Index: 0},
+			NewEntityId(5, 0): {ArchetypeId: 2, Index: 1}},
+		readEntries(storage))
 }
 
 func TestStorageEnsurePage(t *testing.T) {
@@ -220,16 +260,45 @@ func TestStorageEnsurePage(t *testing.T) {
 		PartBuffers: map[PartId][]byte{
 			PartId(shortComponent): {}, // short component in archetype 0 from newTestStore()
 		},
+		PartValues: map[PartId]*valueColumn{},
+		Ticks: map[PartId][]ChangeTicks{
+			PartId(shortComponent): {},
+			PartId(^uint32(1)):     {}, // tags get ticks too
+		},
+		registry: storage.registry,
 	}, *got0)
 
 	got1 := storage.ensurePage(1)
 	assert.NotNil(t, storage.Pages[1])
 	assert.Equal(t, Page{
 		PartBuffers: map[PartId][]byte{}, // archetype 1 from newTestStore() is all tags
+		PartValues:  map[PartId]*valueColumn{},
+		Ticks: map[PartId][]ChangeTicks{
+			PartId(^uint32(3)): {},
+		},
+		registry: storage.registry,
 	}, *got1)
 }
 
-func newTestStore(entries map[EntityId]entry, Pages map[archetypeId]*Page, nextEntity EntityId) *Store {
+func newTestStore(entries map[EntityId]entry, pages map[archetypeId]*Page, nextIndex uint32) *Store {
+	denseEntries := make([]entry, nextIndex)
+	for i := range denseEntries {
+		denseEntries[i].ArchetypeId = noArchetype
+	}
+
+	for entity, entry := range entries {
+		entry.Generation = entity.Generation()
+		denseEntries[entity.Index()] = entry
+	}
+
+	densePages := []*Page{}
+	for archetypeId, page := range pages {
+		if int(archetypeId) >= len(densePages) {
+			densePages = append(densePages, make([]*Page, int(archetypeId)+1-len(densePages))...)
+		}
+		densePages[archetypeId] = page
+	}
+
 	tag1 := partMock(^uint32(1))
 	tag3 := partMock(^uint32(3))
 
@@ -243,19 +312,271 @@ func newTestStore(entries map[EntityId]entry, Pages map[archetypeId]*Page, nextE
 			{tag3},
 			{shortComponent, integerComponent},
 		},
-		ArchetypeMap: map[uint64]archetypeId{
-			crc64.Checksum([]byte{0, 0, 0, 0, 254, 255, 255, 255}, isoTable): 0,
-			crc64.Checksum([]byte{252, 255, 255, 255}, isoTable):             1,
-			crc64.Checksum([]byte{0, 0, 0, 0, 1, 0, 0, 0}, isoTable):         2,
+		ArchetypeMap: map[uint64][]archetypeId{
+			crc64.Checksum([]byte{0, 0, 0, 0, 254, 255, 255, 255}, isoTable): {0},
+			crc64.Checksum([]byte{252, 255, 255, 255}, isoTable):             {1},
+			crc64.Checksum([]byte{0, 0, 0, 0, 1, 0, 0, 0}, isoTable):         {2},
 		},
-		Parts: map[Part]struct{}{
+		Parts: map[PartId]struct{}{
 			shortComponent:   {},
 			integerComponent: {},
 		},
-		Entries:    entries,
-		Pages:      Pages,
-		Mutex:      &sync.Mutex{},
-		NextEntity: nextEntity,
-		Resources:  map[string]any{},
+		Entries:   denseEntries,
+		Pages:     densePages,
+		Mutex:     &sync.Mutex{},
+		Resources: map[string]any{},
+		Tick:      1,
+		registry:  newTestRegistry(),
+	}
+}
+
+func readEntries(store *Store) map[EntityId]entry {
+	entries := map[EntityId]entry{}
+
+	for index, entry := range store.Entries {
+		if entry.ArchetypeId == noArchetype {
+			continue
+		}
+
+		entity := NewEntityId(uint32(index), entry.Generation)
+		entry.Generation = 0
+		entries[entity] = entry
+	}
+
+	return entries
+}
+
+func TestStorageMovePage(t *testing.T) {
+	storage := newTestStore(
+		map[EntityId]entry{
+			0: {ArchetypeId: 2, Index: 0},
+			2: {ArchetypeId: 0, Index: 0},
+			3: {ArchetypeId: 2, Index: 1},
+		},
+		map[archetypeId]*Page{
+			0: {
+				PartBuffers: map[PartId][]byte{
+					PartId(shortComponent): {5, 0},
+				},
+				Entities:  []EntityId{2},
+				Size:      1,
+				DirtySize: 1,
+				registry:  newTestRegistry(),
+			},
+			2: newTestPage(
+				[]EntityId{0, 3},
+				[]byte{34, 1, 78, 0},
+				[]byte{3, 53, 230, 1, 7, 0, 0, 0}),
+		}, 4)
+
+	// drop the integer component from every entity in archetype 2
+	firstIndex := storage.movePage(2, 0, storage.Tick)
+	assert.Equal(t, 1, firstIndex)
+
+	assert.Equal(t, []EntityId{2, 0, 3}, storage.Pages[0].Entities)
+	assert.Equal(t, []byte{5, 0, 34, 1, 78, 0}, storage.Pages[0].PartBuffers[PartId(shortComponent)])
+
+	assert.Equal(t, 0, storage.Pages[2].Size)
+	assert.Empty(t, storage.Pages[2].Entities)
+	assert.Empty(t, storage.Pages[2].PartBuffers[PartId(integerComponent)])
+
+	assert.EqualValues(t,
+		map[EntityId]entry{
+			0: {ArchetypeId: 0, Index: 1},
+			2: {ArchetypeId: 0, Index: 0},
+			3: {ArchetypeId: 0, Index: 2},
+		},
+		readEntries(storage))
+}
+
+func TestStorageArchetypeCollision(t *testing.T) {
+	component1 := partMock(1)
+	component5 := partMock(5)
+
+	storage := NewStore(newTestRegistry())
+	first := storage.NewArchetype([]Part{component1})
+
+	// pretend another signature collides with the first archetype
+	collidingHash := Signature{component5}.hash()
+	storage.ArchetypeMap[collidingHash] = append(storage.ArchetypeMap[collidingHash], first)
+
+	second := storage.NewArchetype([]Part{component5})
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, Signature{component5}, storage.Archetypes[second])
+
+	// both are found again
+	assert.Equal(t, first, storage.NewArchetype([]Part{component1}))
+	assert.Equal(t, second, storage.NewArchetype([]Part{component5}))
+}
+
+func TestStorageArchetypeEdges(t *testing.T) {
+	tag1 := partMock(^uint32(1))
+
+	storage := newTestStore(
+		map[EntityId]entry{
+			0: {ArchetypeId: 2, Index: 0},
+		},
+		map[archetypeId]*Page{
+			2: newTestPage(
+				[]EntityId{0},
+				[]byte{0, 0},
+				[]byte{0, 0, 0, 0}),
+		}, 1)
+
+	assert.True(t, storage.DeletePart(0, integerComponent, storage.Tick))
+	assert.True(t, storage.AddPart(0, tag1, storage.Tick))
+	assert.Equal(t, archetypeId(0), readEntries(storage)[0].ArchetypeId)
+
+	assert.Equal(t, map[PartId]archetypeId{integerComponent: 3}, storage.edgesOf(2).deleted)
+	assert.Equal(t, map[PartId]archetypeId{tag1: 0}, storage.edgesOf(3).added)
+
+	// cached edges are followed without building signatures
+	storage.Archetypes[3] = Signature{}
+	assert.Equal(t, archetypeId(0), storage.archetypeWith(3, tag1))
+	assert.Equal(t, archetypeId(3), storage.archetypeWithout(2, integerComponent))
+}
+
+func TestStorageCollidingHash(t *testing.T) {
+	tag1 := partMock(^uint32(1))
+	component5 := partMock(5)
+
+	storage := NewStore(newTestRegistry())
+	storage.hash = func(Signature) uint64 { return 0 }
+
+	short := storage.NewArchetype([]Part{shortComponent})
+	integer := storage.NewArchetype([]Part{integerComponent})
+	both := storage.NewArchetype([]Part{shortComponent, integerComponent})
+	tagged := storage.NewArchetype([]Part{tag1, component5})
+
+	assert.Equal(t, []archetypeId{0, 1, 2, 3}, []archetypeId{short, integer, both, tagged})
+	assert.Equal(t, map[uint64][]archetypeId{0: {0, 1, 2, 3}}, storage.ArchetypeMap)
+
+	// repeats find their own archetype in the shared bucket
+	assert.Equal(t, integer, storage.NewArchetype([]Part{integerComponent}))
+	assert.Equal(t, tagged, storage.NewArchetype([]Part{component5, tag1}))
+
+	// entities move between colliding archetypes with their data
+	entity := storage.Grow(short, 1, storage.Tick)[0]
+	*(*uint16)(unsafe.Pointer(&storage.GetComponent(entity, PartId(shortComponent))[0])) = 300
+
+	assert.True(t, storage.AddPart(entity, integerComponent, storage.Tick))
+	assert.Equal(t, both, storage.Entries[entity.Index()].ArchetypeId)
+	assert.Equal(t, []byte{44, 1}, storage.GetComponent(entity, PartId(shortComponent)))
+	storage.GetComponent(entity, PartId(integerComponent))[0] = 9
+
+	assert.True(t, storage.DeletePart(entity, shortComponent, storage.Tick))
+	assert.Equal(t, integer, storage.Entries[entity.Index()].ArchetypeId)
+	assert.Equal(t, []byte{9, 0, 0, 0}, storage.GetComponent(entity, PartId(integerComponent)))
+
+	// remapping rebuilds the bucket with the same hash
+	storage.Remap(map[PartId]PartId{
+		shortComponent:   integerComponent,
+		integerComponent: shortComponent,
+		tag1:             tag1,
+		component5:       component5,
+	})
+	assert.Equal(t, map[uint64][]archetypeId{0: {0, 1, 2, 3}}, storage.ArchetypeMap)
+	assert.Equal(t, short, storage.NewArchetype([]Part{integerComponent}))
+	assert.Equal(t, integer, storage.NewArchetype([]Part{shortComponent}))
+}
+
+func TestStorageChangeTicks(t *testing.T) {
+	storage := NewStore(newTestRegistry())
+	short := storage.NewArchetype([]Part{shortComponent})
+	both := storage.NewArchetype([]Part{shortComponent, integerComponent})
+
+	entities := storage.Grow(short, 3, storage.Tick)
+	assert.Equal(t, uint64(1), storage.AdvanceTick())
+
+	shortTicks := storage.Pages[short].Ticks[PartId(shortComponent)]
+	assert.Equal(t, []ChangeTicks{{Added: 1, Changed: 1}, {Added: 1, Changed: 1}, {Added: 1, Changed: 1}}, shortTicks)
+	for i := range shortTicks {
+		shortTicks[i].Changed = uint64(i) + 3
+	}
+
+	// components kept through a move keep their ticks, new ones are added at the current tick
+	assert.True(t, storage.AddPart(entities[0], integerComponent, storage.Tick))
+	assert.Equal(t, []ChangeTicks{{Added: 1, Changed: 3}}, storage.Pages[both].Ticks[PartId(shortComponent)])
+	assert.Equal(t, []ChangeTicks{{Added: 2, Changed: 2}}, storage.Pages[both].Ticks[PartId(integerComponent)])
+
+	// deleting swaps the ticks of the last row into place
+	assert.Equal(t, []ChangeTicks{{Added: 1, Changed: 5}, {Added: 1, Changed: 4}}, storage.Pages[short].Ticks[PartId(shortComponent)])
+	storage.Delete(entities[2], storage.Tick)
+	assert.Equal(t, []ChangeTicks{{Added: 1, Changed: 4}}, storage.Pages[short].Ticks[PartId(shortComponent)])
+
+	// bulk moves keep ticks too
+	storage.movePage(short, both, storage.Tick)
+	assert.Empty(t, storage.Pages[short].Ticks[PartId(shortComponent)])
+	assert.Equal(t, []ChangeTicks{{Added: 1, Changed: 3}, {Added: 1, Changed: 4}}, storage.Pages[both].Ticks[PartId(shortComponent)])
+	assert.Equal(t, []ChangeTicks{{Added: 2, Changed: 2}, {Added: 2, Changed: 2}}, storage.Pages[both].Ticks[PartId(integerComponent)])
+}
+
+func TestStorageRemovals(t *testing.T) {
+	storage := NewStore(newTestRegistry())
+	both := storage.NewArchetype([]Part{shortComponent, integerComponent})
+
+	// nothing is recorded until the log is read
+	entities := storage.Grow(both, 4, storage.Tick)
+	storage.Delete(entities[3], storage.Tick)
+	removed, _, _ := storage.Removals(PartId(shortComponent))
+	assert.Empty(t, removed)
+	deleted, _ := storage.Deletions()
+	assert.Empty(t, deleted)
+
+	for i, entity := range entities[:3] {
+		copy(storage.GetComponent(entity, PartId(shortComponent)), []byte{byte(i + 1), 0})
 	}
+
+	assert.True(t, storage.DeletePart(entities[0], shortComponent, storage.Tick))
+	storage.AdvanceTick()
+	storage.Delete(entities[1], storage.Tick)
+	assert.True(t, storage.DeletePart(entities[2], integerComponent, storage.Tick))
+
+	removed, ticks, pointer := storage.Removals(PartId(shortComponent))
+	assert.Equal(t, []EntityId{entities[0], entities[1]}, removed)
+	assert.Equal(t, []uint64{1, 2}, ticks)
+	assert.Equal(t, []byte{1, 0, 2, 0}, unsafe.Slice((*byte)(pointer), 4))
+
+	deleted, ticks = storage.Deletions()
+	assert.Equal(t, []EntityId{entities[1]}, deleted)
+	assert.Equal(t, []uint64{2}, ticks)
+
+	// pruning keeps removals from the tick onwards
+	storage.PruneRemovals(2)
+	removed, ticks, pointer = storage.Removals(PartId(shortComponent))
+	assert.Equal(t, []EntityId{entities[1]}, removed)
+	assert.Equal(t, []uint64{2}, ticks)
+	assert.Equal(t, []byte{2, 0}, unsafe.Slice((*byte)(pointer), 2))
+
+	storage.PruneRemovals(3)
+	removed, _, _ = storage.Removals(PartId(shortComponent))
+	assert.Empty(t, removed)
+	deleted, _ = storage.Deletions()
+	assert.Empty(t, deleted)
+}
+
+func TestStorageRecordedRemovals(t *testing.T) {
+	registry := newTestRegistry()
+	registry.RecordRemovals(PartId(shortComponent))
+	registry.RecordDeletions()
+	storage := NewStore(registry)
+	both := storage.NewArchetype([]Part{shortComponent, integerComponent})
+
+	// parts the registry records are recorded before the log is first read
+	entities := storage.Grow(both, 3, storage.Tick)
+	copy(storage.GetComponent(entities[0], PartId(shortComponent)), []byte{7, 0})
+	assert.True(t, storage.DeletePart(entities[0], shortComponent, storage.Tick))
+	storage.Delete(entities[1], storage.Tick)
+
+	removed, ticks, pointer := storage.Removals(PartId(shortComponent))
+	assert.Equal(t, []EntityId{entities[0], entities[1]}, removed)
+	assert.Equal(t, []uint64{1, 1}, ticks)
+	assert.Equal(t, []byte{7, 0}, unsafe.Slice((*byte)(pointer), 2))
+
+	deleted, _ := storage.Deletions()
+	assert.Equal(t, []EntityId{entities[1]}, deleted)
+
+	// other parts are still only recorded once read
+	removed, _, _ = storage.Removals(PartId(integerComponent))
+	assert.Empty(t, removed)
 }
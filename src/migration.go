@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// Register a migration from an old version of a component's data type, run over saved data of that version when a world is deserialized.
+// Components start at version zero, and registering a migration from a version makes the component at least one version newer.
+// Saved data without a migration is copied field by field when only fields were added or removed.
+func AddMigration[Old any, Data any](registry *Registry, component Component[Data], version uint32, migrate func(old *Old, data *Data)) {
+	registry.parts.AddMigration(storage.PartId(component), version, reflect.TypeFor[Old](),
+		func(old unsafe.Pointer, data unsafe.Pointer) {
+			migrate((*Old)(old), (*Data)(data))
+		})
+}
+
+// Convert the saved data of a part to the layout it has in the registry, failing if there is no migration path.
+func migratePart(registry *Registry, store *storage.Store, partId storage.PartId, saved storage.PartSchema) error {
+	schema, _ := registry.parts.Schema(partId)
+
+	if saved.Version != schema.Version {
+		if migration, exists := registry.parts.Migration(partId, saved.Version); exists {
+			if !migration.Schema.SameLayout(saved) {
+				return fmt.Errorf("%w: %q version %d doesn't match the layout of it's migration", ErrIncompatibleParts, saved.Name, saved.Version)
+			}
+
+			return wrapIncompatible(store.Convert(partId, saved.Size, migration.Type, migration.Convert))
+		}
+	}
+
+	if schema.SameLayout(saved) {
+		return nil
+	}
+
+	if saved.Pointers && schema.Kind != reflect.Invalid && schema.Kind == saved.Kind {
+		// parts with pointers are saved by value, and their fields are matched by name when decoded
+		return nil
+	}
+
+	convert, compatible := schema.CopyFrom(saved)
+	if !compatible {
+		return fmt.Errorf("%w: no migration for %q from version %d", ErrIncompatibleParts, saved.Name, saved.Version)
+	}
+
+	return wrapIncompatible(store.Convert(partId, saved.Size, nil, convert))
+}
+
+func wrapIncompatible(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrIncompatibleParts, err)
+}
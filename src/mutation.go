@@ -0,0 +1,129 @@
+package main
+
+import (
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A set of components/tags to add and remove from an entity, applied with a single archetype move.
+type Mutation struct {
+	world   *World
+	entity  Entity
+	added   []storage.Part
+	removed []storage.Part
+}
+
+// A set of components/tags to add and remove from every entity matching a filter, moving whole pages at once.
+type QueryMutation struct {
+	world   *World
+	filter  Matcher
+	added   []storage.Part
+	removed []storage.Part
+}
+
+// Start a set of changes to the components/tags of an entity.
+func (world *World) Mutate(entity Entity) *Mutation {
+	return &Mutation{world: world, entity: entity}
+}
+
+// Start a set of changes to the components/tags of every entity that matches a filter.
+func (world *World) MutateQuery(filter Matcher) *QueryMutation {
+	return &QueryMutation{world: world, filter: filter}
+}
+
+// Add components/tags, components given with Component.With have their data set.
+func (mutation *Mutation) Add(parts ...storage.Part) *Mutation {
+	mutation.added = append(mutation.added, parts...)
+	return mutation
+}
+
+// Remove components/tags, taking priority over additions.
+func (mutation *Mutation) Remove(parts ...storage.Part) *Mutation {
+	mutation.removed = append(mutation.removed, parts...)
+	return mutation
+}
+
+// Apply the changes, moving the entity at most once.
+// Fails if the entity doesn't exist.
+func (mutation *Mutation) Apply() (success bool) {
+	world := mutation.world
+	store := world.store
+	entity := storage.EntityId(mutation.entity)
+
+	world.beforeRemove(mutation.entity, mutation.removed)
+
+	before, _ := store.PartsOf(entity)
+	if !store.ChangeParts(entity, mutation.added, mutation.removed, world.changeTick()) {
+		return false
+	}
+
+	page, index, _ := store.Locate(entity)
+	world.writeValuesAt(page, index, 1, mutation.added)
+	world.afterAdd(mutation.entity, before, mutation.added)
+	return true
+}
+
+// Add components/tags, components given with Component.With have their data set.
+func (mutation *QueryMutation) Add(parts ...storage.Part) *QueryMutation {
+	mutation.added = append(mutation.added, parts...)
+	return mutation
+}
+
+// Remove components/tags, taking priority over additions.
+func (mutation *QueryMutation) Remove(parts ...storage.Part) *QueryMutation {
+	mutation.removed = append(mutation.removed, parts...)
+	return mutation
+}
+
+// Apply the changes, moving each matching page of entities at once.
+// Pages where only some entities match, such as with Filter.Changed, have their entities moved one by one.
+// Returns the number of entities that matched.
+func (mutation *QueryMutation) Apply() (count int) {
+	world := mutation.world
+	store := world.store
+
+	// find every page and entity before moving any, pages can't change while filtering
+	type match struct {
+		page *storage.Page
+		size int
+	}
+	matches := []match{}
+	entities := []Entity{}
+	for page, rows := range mutation.filter.filter(world) {
+		if rows == nil {
+			matches = append(matches, match{page, page.Size})
+			continue
+		}
+
+		for i, entity := range page.Entities {
+			if rows(i) {
+				entities = append(entities, Entity(entity))
+			}
+		}
+	}
+
+	for _, match := range matches {
+		if match.page.Size == 0 {
+			continue
+		}
+
+		before, _ := store.PartsOf(match.page.Entities[0])
+		world.beforeChange(match.page, 0, match.size, before, before.Change(nil, mutation.removed))
+
+		// a matched page that other pages moved into keeps it's archetype, and the rows moved in are past it's matched size,
+		// so only the rows that matched are changed
+		page, firstIndex, n := store.ChangePageParts(match.page, mutation.added, mutation.removed, world.changeTick())
+		n = min(n, match.size)
+		world.writeValuesAt(page, firstIndex, n, mutation.added)
+
+		after, _ := store.PartsOf(page.Entities[firstIndex])
+		world.afterChange(page, firstIndex, n, before, after, mutation.added)
+		count += match.size
+	}
+
+	for _, entity := range entities {
+		world.Mutate(entity).Add(mutation.added...).Remove(mutation.removed...).Apply()
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A kind of change to the entities of a world.
+type LifecycleKind uint8
+
+const (
+	EntitySpawned LifecycleKind = iota
+	EntityDeleted
+	ComponentAdded
+	ComponentRemoved
+	TagAdded
+	TagRemoved
+)
+
+// A change to an entity of a world, published to observing buses.
+// Part is the component or tag added or removed, and is zero for entities spawned or deleted.
+type LifecycleEvent struct {
+	Kind   LifecycleKind
+	Entity Entity
+	Part   storage.PartId
+}
+
+// A bus published to when entities matching a filter change.
+type observer struct {
+	filter Filter
+	bus    *Bus[LifecycleEvent]
+}
+
+// Publish lifecycle events onto a bus for entities matching a filter.
+// Additions and spawns are matched after the change, removals and deletions before it.
+// Events are published while the change is made, listeners should queue structural changes with World.Commands like hooks.
+func (world *World) Observe(bus *Bus[LifecycleEvent], filter Filter) {
+	world.hooks.observers = append(world.hooks.observers, observer{filter, bus})
+}
+
+// Stop publishing lifecycle events onto a bus.
+func (world *World) StopObserving(bus *Bus[LifecycleEvent]) {
+	for i, observer := range world.hooks.observers {
+		if observer.bus == bus {
+			world.hooks.observers = append(world.hooks.observers[:i:i], world.hooks.observers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish an event for each row of a page that matches the filter of each observer.
+func (world *World) publish(kind LifecycleKind, partId storage.PartId, page *storage.Page, firstIndex int, count int, archetype storage.Signature) {
+	for _, observer := range world.hooks.observers {
+		if !observer.filter.match(&archetype) {
+			continue
+		}
+
+		rows := observer.filter.rows(world, &archetype, page)
+		for i := firstIndex; i < firstIndex+count; i++ {
+			if rows == nil || rows(i) {
+				observer.bus.Publish(LifecycleEvent{kind, Entity(page.Entities[i]), partId})
+			}
+		}
+	}
+}
+
+// Publish events for the parts rows of a page are about to lose.
+func (world *World) publishRemoved(page *storage.Page, firstIndex int, count int, before storage.Signature, after storage.Signature) {
+	for _, partId := range before {
+		if after.ContainsSingle(partId) {
+			continue
+		}
+
+		kind := ComponentRemoved
+		if _, isComponent := world.registry.parts.PartType(partId); !isComponent {
+			kind = TagRemoved
+		}
+
+		world.publish(kind, partId, page, firstIndex, count, before)
+	}
+}
+
+// Publish events for the parts rows of a page gained.
+func (world *World) publishAdded(page *storage.Page, firstIndex int, count int, before storage.Signature, after storage.Signature) {
+	for _, partId := range after {
+		if before.ContainsSingle(partId) {
+			continue
+		}
+
+		kind := ComponentAdded
+		if _, isComponent := world.registry.parts.PartType(partId); !isComponent {
+			kind = TagAdded
+		}
+
+		world.publish(kind, partId, page, firstIndex, count, after)
+	}
+}
@@ -2,43 +2,88 @@ package main
 
 import (
 	"iter"
-	"unsafe"
 
 	"github.com/averagestardust/wecs/internal/storage"
 )
 
 // A pair of components often used together
-type Pair[T any, U any] [2]storage.PartId
+type Pair[T any, U any] struct {
+	a Term[T]
+	b Term[U]
+}
+
+// A row of data from two components of one entity, pointing into the world.
+type Row2[A, B any] struct {
+	A *A
+	B *B
+}
 
 // Create a pair of components to query for data together
-func NewPair[T any, U any](a Component[T], b Component[U]) Pair[T, U] {
-	return Pair[T, U]{storage.PartId(a), storage.PartId(b)}
+// Components made optional with Optional yield nil for entities without them.
+func NewPair[T any, U any](a Term[T], b Term[U]) Pair[T, U] {
+	return Pair[T, U]{a, b}
 }
 
 // Return an iterator of data from two component types from all entities that match a filter.
-func (pair Pair[T, U]) Query(world *World, filter Filter) iter.Seq2[*T, *U] {
+// Entities without both components are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (pair Pair[T, U]) Query(world *World, filter Matcher) iter.Seq2[*T, *U] {
 	return func(yield func(*T, *U) bool) {
-		for page := range filter.filter(world.store) {
-			nextA, stopA := iter.Pull(page.GetComponentIter(pair[0]))
-			nextB, stopB := iter.Pull(page.GetComponentIter(pair[1]))
-			defer stopA()
-			defer stopB()
-
-			for {
-				aBytes, done := nextA()
-				bBytes, _ := nextB()
-				aComponent := (*T)(unsafe.Pointer(&aBytes))
-				bComponent := (*U)(unsafe.Pointer(&bBytes))
-
-				// yield both components
-				if !yield(aComponent, bComponent) {
-					return
+		for _, row := range pair.each(world, filter, false) {
+			if !yield(row.A, row.B) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of data from two component types from all entities that match a filter, marking each as changed.
+// Entities without both components are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept.
+func (pair Pair[T, U]) QueryMut(world *World, filter Matcher) iter.Seq2[*T, *U] {
+	return func(yield func(*T, *U) bool) {
+		for _, row := range pair.each(world, filter, true) {
+			if !yield(row.A, row.B) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from two component types, from all entities that match a filter.
+// Entities without both components are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (pair Pair[T, U]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, Row2[T, U]] {
+	return pair.each(world, filter, false)
+}
+
+// iterate the rows of matching entities, marking their components as changed if mark is set
+func (pair Pair[T, U]) each(world *World, filter Matcher, mark bool) iter.Seq2[Entity, Row2[T, U]] {
+	return func(yield func(Entity, Row2[T, U]) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			aValues, aMatches := pair.a.column(page)
+			bValues, bMatches := pair.b.column(page)
+			if !aMatches || !bMatches {
+				continue
+			}
+
+			var ticks [][]storage.ChangeTicks
+			if mark {
+				ticks = changeTicks(page, pair.a, pair.b)
+			}
+
+			for i := range page.Size {
+				if rows != nil && !rows(i) {
+					continue
 				}
-				if done {
+
+				markChanged(ticks, i, tick)
+				if !yield(Entity(page.Entities[i]), Row2[T, U]{at(aValues, i), at(bValues, i)}) {
 					return
 				}
 			}
 		}
-
 	}
 }
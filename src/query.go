@@ -0,0 +1,60 @@
+package main
+
+import (
+	"iter"
+
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A filter that remembers which archetypes it matched, so each archetype is only checked once.
+// Archetypes created after the last query are checked the next time it's used.
+// Can be used in place of a filter, but shouldn't be shared by systems running at the same time.
+type CachedQuery struct {
+	layers  Filter
+	store   *storage.Store
+	checked int
+	matches []int
+}
+
+// Create a cached query from a filter.
+// The filter shouldn't be changed afterwards.
+func NewCachedQuery(filter Filter) *CachedQuery {
+	return &CachedQuery{layers: filter}
+}
+
+// Check any archetypes created since the last update.
+// Using the query with a different world starts the cache over.
+func (query *CachedQuery) update(store *storage.Store) {
+	if query.store != store {
+		query.store = store
+		query.checked = 0
+		query.matches = query.matches[:0]
+	}
+
+	for ; query.checked < len(store.Archetypes); query.checked++ {
+		if query.layers.match(&store.Archetypes[query.checked]) {
+			query.matches = append(query.matches, query.checked)
+		}
+	}
+}
+
+// Return an iterator of the pages of matching archetypes, in the order archetypes were created.
+func (query *CachedQuery) filter(world *World) iter.Seq2[*storage.Page, func(index int) bool] {
+	store := world.store
+	query.update(store)
+	matches := query.matches
+
+	return func(yield func(page *storage.Page, rows func(index int) bool) bool) {
+		for _, archetypeId := range matches {
+			// archetypes might not have a page until entities are added
+			if archetypeId >= len(store.Pages) || store.Pages[archetypeId] == nil {
+				continue
+			}
+
+			page := store.Pages[archetypeId]
+			if !yield(page, query.layers.rows(world, &store.Archetypes[archetypeId], page)) {
+				return
+			}
+		}
+	}
+}
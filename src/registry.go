@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A set of component and tag types.
+// Worlds sharing a registry agree on the ids of their components and tags,
+// while worlds with separate registries are fully isolated from each other.
+type Registry struct {
+	parts *storage.Registry
+}
+
+// Create a new registry to create components and tags with.
+func NewRegistry() *Registry {
+	return &Registry{
+		parts: storage.NewRegistry(),
+	}
+}
+
+// Record deleted entities in every world using the registry, from the moment the world is created.
+// Without this deletions are only recorded once World.Deleted is first used, missing those made before it.
+// Should be used during world initialization.
+func (registry *Registry) RecordDeleted() {
+	registry.parts.RecordDeletions()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Runs stages of systems in order, with the systems of each stage running at the same time.
+// Systems in a stage must not make structural changes directly, or write components another system in the stage uses.
+type Scheduler struct {
+	stages   [][]System
+	lastRuns [][]uint64 // the ticks systems that aren't buffered last ran at, by stage and position
+}
+
+// Create a new scheduler without any stages.
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add a stage of systems that run at the same time, after all the previous stages.
+func (scheduler *Scheduler) AddStage(systems ...System) *Scheduler {
+	scheduler.stages = append(scheduler.stages, systems)
+	scheduler.lastRuns = append(scheduler.lastRuns, make([]uint64, len(systems)))
+	return scheduler
+}
+
+// Run every stage of systems.
+// After each stage the commands of it's systems are applied in the order the systems were added,
+// so the result doesn't depend on which system finished first.
+// Removals and deletions every system has seen are forgotten afterwards.
+func (scheduler *Scheduler) Run(world *World, delta time.Duration) {
+	// every system has seen what was removed before this run once it finishes
+	defer world.store.PruneRemovals(world.store.Tick)
+
+	for stageIndex, stage := range scheduler.stages {
+		buffers := make([]*Commands, len(stage))
+
+		// ticks are given out in order before any system starts
+		ticks := make([]uint64, len(stage))
+		for i := range stage {
+			ticks[i] = world.store.AdvanceTick()
+		}
+
+		if len(stage) == 1 {
+			buffers[0] = scheduler.run(world, delta, stageIndex, 0, ticks[0])
+		} else {
+			var group sync.WaitGroup
+			for i := range stage {
+				group.Add(1)
+				go func() {
+					defer group.Done()
+					buffers[i] = scheduler.run(world, delta, stageIndex, i, ticks[i])
+				}()
+			}
+			group.Wait()
+		}
+
+		for _, commands := range buffers {
+			commands.Flush(world)
+		}
+		world.commands.Flush(world)
+	}
+}
+
+// Run a system of a stage at a tick, returning the commands it queued without applying them.
+func (scheduler *Scheduler) run(world *World, delta time.Duration, stage int, index int, tick uint64) *Commands {
+	system := scheduler.stages[stage][index]
+	if system, buffered := system.(bufferedSystem); buffered {
+		return system.run(world, delta, tick)
+	}
+
+	// other systems run on a view with a buffer held here, so their commands are still applied in order
+	commands := NewCommands()
+	system.Run(world.view(commands, tick, scheduler.lastRuns[stage][index]), delta)
+	scheduler.lastRuns[stage][index] = tick
+
+	return commands
+}
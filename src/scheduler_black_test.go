@@ -0,0 +1,187 @@
+package main_test
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	wecs "github.com/averagestardust/wecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerStages(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Flag := wecs.NewTag(registry, "flag")
+
+	spawn := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		world.Commands().NewBatch(5, Integer.With(1))
+	})
+
+	// the second stage sees the entities spawned by the first
+	seen := 0
+	flag := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		for entity := range world.Query(wecs.NewFilter().Exactly(Integer)) {
+			world.Commands().Add(entity, Flag)
+			seen++
+		}
+	})
+
+	scheduler := wecs.NewScheduler().AddStage(spawn).AddStage(flag)
+	scheduler.Run(world, time.Second)
+
+	assert.Equal(t, 5, seen)
+	assert.Equal(t, time.Second, spawn.Runtime())
+	assert.Equal(t, time.Second, flag.Runtime())
+
+	count := 0
+	for range world.Query(wecs.NewFilter().Exactly(Integer, Flag)) {
+		count++
+	}
+	assert.Equal(t, 5, count)
+}
+
+func TestSchedulerDeterministic(t *testing.T) {
+	var expected map[wecs.Entity]uint32
+
+	for range 20 {
+		registry := wecs.NewRegistry()
+		world := wecs.NewWorld(registry)
+		Integer := wecs.NewComponent[uint32](registry, "integer")
+
+		initial := []wecs.Entity{}
+		for i := range 40 {
+			initial = append(initial, world.New(Integer.With(uint32(i))))
+		}
+
+		// systems finish in a random order but their commands are applied in the order they were added
+		systems := []wecs.System{}
+		for n := range 4 {
+			systems = append(systems, wecs.NewSystem(n, func(world *wecs.World, state *int, delta time.Duration, runtime time.Duration) {
+				time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+
+				for i := range 10 {
+					world.QueueDelete(initial[*state*10+i])
+					world.Commands().New(Integer.With(uint32(*state*100 + i)))
+				}
+			}))
+		}
+
+		wecs.NewScheduler().AddStage(systems...).Run(world, time.Second)
+
+		values := map[wecs.Entity]uint32{}
+		for entity := range world.Query(wecs.NewFilter().IncludeExact(Integer)) {
+			values[entity] = *Integer.Get(world, entity)
+		}
+		assert.Len(t, values, 40)
+
+		if expected == nil {
+			expected = values
+		}
+		assert.Equal(t, expected, values)
+	}
+}
+
+// A system implemented outside of the package.
+type countingSystem struct {
+	Integer wecs.Component[uint32]
+	changed int
+	runtime time.Duration
+}
+
+func (system *countingSystem) Run(world *wecs.World, delta time.Duration) {
+	system.changed = 0
+	for entity := range world.Query(wecs.NewFilter().Changed(system.Integer)) {
+		system.changed++
+		world.Commands().Delete(entity)
+	}
+	system.runtime += delta
+}
+
+func (system *countingSystem) State() any {
+	return system
+}
+
+func (system *countingSystem) Runtime() time.Duration {
+	return system.runtime
+}
+
+func (system *countingSystem) SetRuntime(runtime time.Duration) {
+	system.runtime = runtime
+}
+
+func TestSchedulerExternalSystem(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+
+	first := world.New(Integer)
+	second := world.New(Integer)
+
+	writer := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {
+		if world.Alive(first) {
+			Integer.Set(world, first, 1)
+		}
+	})
+	counter := &countingSystem{Integer: Integer}
+	other := wecs.NewSystem(struct{}{}, func(world *wecs.World, state *struct{}, delta time.Duration, runtime time.Duration) {})
+	scheduler := wecs.NewScheduler().AddStage(writer).AddStage(counter, other)
+
+	// systems from other packages run in stages, with their commands held until the stage ends
+	scheduler.Run(world, time.Second)
+	assert.Equal(t, 2, counter.changed)
+	assert.False(t, world.Exists(first))
+	assert.False(t, world.Exists(second))
+	assert.Equal(t, time.Second, counter.Runtime())
+
+	// and only see what changed since they last ran
+	third := world.New(Integer)
+	scheduler.Run(world, time.Second)
+	assert.Equal(t, 1, counter.changed)
+	assert.False(t, world.Exists(third))
+
+	scheduler.Run(world, time.Second)
+	assert.Equal(t, 0, counter.changed)
+}
+
+func TestSchedulerChangeTicks(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Flag := wecs.NewTag(registry, "flag")
+
+	type seen struct{ added, changed, flagged int }
+	count := func(world *wecs.World, state *seen) {
+		*state = seen{}
+		for range world.Query(wecs.NewFilter().Added(Integer)) {
+			state.added++
+		}
+		for range world.Query(wecs.NewFilter().Changed(Integer)) {
+			state.changed++
+		}
+		for range world.Query(wecs.NewFilter().Added(Flag)) {
+			state.flagged++
+		}
+	}
+
+	// a system that spawns and writes entities directly marks both with it's own tick
+	spawner := wecs.NewSystem(seen{}, func(world *wecs.World, state *seen, delta time.Duration, runtime time.Duration) {
+		count(world, state)
+		entity := world.New(Integer)
+		*Integer.Mut(world, entity) = 1
+		Flag.Add(world, entity)
+	})
+	reader := wecs.NewSystem(seen{}, func(world *wecs.World, state *seen, delta time.Duration, runtime time.Duration) {
+		count(world, state)
+	})
+	scheduler := wecs.NewScheduler().AddStage(spawner).AddStage(reader)
+
+	for range 3 {
+		scheduler.Run(world, time.Second)
+
+		// the spawner never sees it's own additions or writes, the reader sees both from the last run
+		assert.Equal(t, seen{}, *spawner.State().(*seen))
+		assert.Equal(t, seen{added: 1, changed: 1, flagged: 1}, *reader.State().(*seen))
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"time"
 
@@ -18,11 +19,12 @@ type systemSave struct {
 type worldSave struct {
 	_           struct{} `cbor:",toarray"`
 	Store       *storage.Store
-	DeleteQueue map[Entity]struct{}
-	PartHash    uint64
+	DeleteQueue []Entity
+	Parts       map[storage.PartId]storage.PartSchema
 }
 
 var ErrIncompatibleParts = errors.New("can't deserialize because existing parts don't match save")
+var ErrUnsaveableParts = errors.New("can't serialize because parts with pointers have unexported fields")
 
 func SerializeSystem(system System, writer io.Writer) (err error) {
 	return Serialize(systemSave{
@@ -43,39 +45,81 @@ func DeserializeSystem[T any](callback systemCallback[T], reader io.Reader) (sys
 	return system, err
 }
 
+// Save a world along with the schema of every component and tag it uses.
+// Queued deletions are saved and applied on load, other queued commands are not saved.
+// Fails if a component with pointers used by the world has unexported fields, as they can't be saved by value.
 func SerializeWorld(world *World, writer io.Writer) (err error) {
-	partHash := storage.HashUsedParts(world.store)
+	if err = world.store.CheckValues(); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnsaveableParts, err)
+	}
 
 	return Serialize(worldSave{
 		Store:       world.store,
-		DeleteQueue: world.deleteQueue,
-		PartHash:    partHash,
+		DeleteQueue: world.commands.deletions(),
+		Parts:       world.store.Schemas(),
 	}, writer)
 }
 
-func DeserializeWorld(reader io.Reader) (world *World, err error) {
+// Load a world saved with SerializeWorld.
+// Components and tags are matched to the registry by name, so their ids and creation order may differ from the save.
+// Components saved with an older layout are migrated to the current layout.
+func DeserializeWorld(registry *Registry, reader io.Reader) (world *World, err error) {
 	save, err := Deserialize[*worldSave](reader)
 	if err != nil {
 		return nil, err
 	}
 
-	world = NewWorld()
-	world.store = save.Store
-	world.deleteQueue = save.DeleteQueue
-	world.EmptyDeleteQueue()
+	partIds, err := remapParts(registry, save.Parts)
+	if err != nil {
+		return nil, err
+	}
+
+	store := save.Store
+	store.SetRegistry(registry.parts)
+	store.Remap(partIds)
 
-	if save.PartHash != storage.HashUsedParts(world.store) {
-		return nil, ErrIncompatibleParts
+	for savedId, savedSchema := range save.Parts {
+		err = migratePart(registry, store, partIds[savedId], savedSchema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = wrapIncompatible(store.DecodeValues())
+	if err != nil {
+		return nil, err
+	}
+
+	world = NewWorld(registry)
+	world.store = store
+	for _, entity := range save.DeleteQueue {
+		world.Delete(entity)
 	}
 
 	return world, err
 }
 
+// Match the saved ids of parts to the ids of parts with the same name in a registry.
+func remapParts(registry *Registry, schemas map[storage.PartId]storage.PartSchema) (partIds map[storage.PartId]storage.PartId, err error) {
+	partIds = map[storage.PartId]storage.PartId{}
+
+	for savedId, savedSchema := range schemas {
+		partId, exists := registry.parts.Lookup(savedSchema.Name)
+		if !exists {
+			return nil, fmt.Errorf("%w: %q is not registered", ErrIncompatibleParts, savedSchema.Name)
+		}
+
+		partIds[savedId] = partId
+	}
+
+	return partIds, nil
+}
+
 func Serialize(object any, writer io.Writer) (err error) {
 	return cbor.NewEncoder(writer).Encode(object)
 }
 
 func Deserialize[T any](reader io.Reader) (object T, err error) {
-	err = cbor.NewDecoder(reader).Decode(object)
+	err = cbor.NewDecoder(reader).Decode(&object)
 	return
 }
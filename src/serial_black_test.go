@@ -0,0 +1,271 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	wecs "github.com/averagestardust/wecs"
+	"github.com/stretchr/testify/assert"
+)
+
+type vector struct {
+	X float32
+	Y float32
+}
+
+func TestSerialWorld(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Integer := wecs.NewComponent[uint32](registry, "integer")
+	Vector := wecs.NewComponent[vector](registry, "vector")
+	Flag := wecs.NewTag(registry, "flag")
+
+	entityA := world.New(Integer, Flag)
+	entityB := world.New(Integer, Vector)
+	*Integer.Get(world, entityA) = 7
+	*Integer.Get(world, entityB) = 12
+	*Vector.Get(world, entityB) = vector{3, -4}
+
+	buffer := bytes.Buffer{}
+	assert.NoError(t, wecs.SerializeWorld(world, &buffer))
+
+	// register in a different order, so every id changes
+	loadRegistry := wecs.NewRegistry()
+	LoadFlag := wecs.NewTag(loadRegistry, "flag")
+	wecs.NewTag(loadRegistry, "unused")
+	LoadVector := wecs.NewComponent[vector](loadRegistry, "vector")
+	LoadInteger := wecs.NewComponent[uint32](loadRegistry, "integer")
+
+	loaded, err := wecs.DeserializeWorld(loadRegistry, &buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, loaded.Alive(entityA))
+	assert.True(t, loaded.Alive(entityB))
+
+	assert.True(t, LoadFlag.Has(loaded, entityA))
+	assert.False(t, LoadVector.Has(loaded, entityA))
+	assert.Equal(t, uint32(7), *LoadInteger.Get(loaded, entityA))
+
+	assert.False(t, LoadFlag.Has(loaded, entityB))
+	assert.Equal(t, uint32(12), *LoadInteger.Get(loaded, entityB))
+	assert.Equal(t, vector{3, -4}, *LoadVector.Get(loaded, entityB))
+
+	count := 0
+	for range loaded.Query(wecs.NewFilter().IncludeExact(LoadInteger)) {
+		count++
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestSerialWorldIncompatible(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Vector := wecs.NewComponent[vector](registry, "vector")
+	world.New(Vector)
+
+	save := bytes.Buffer{}
+	assert.NoError(t, wecs.SerializeWorld(world, &save))
+
+	// same name, field changed kind without a migration
+	changedRegistry := wecs.NewRegistry()
+	wecs.NewComponent[struct{ X, Y int32 }](changedRegistry, "vector")
+
+	_, err := wecs.DeserializeWorld(changedRegistry, bytes.NewReader(save.Bytes()))
+	assert.ErrorIs(t, err, wecs.ErrIncompatibleParts)
+	assert.ErrorContains(t, err, `"vector"`)
+
+	// missing name
+	missingRegistry := wecs.NewRegistry()
+	wecs.NewComponent[vector](missingRegistry, "position")
+
+	_, err = wecs.DeserializeWorld(missingRegistry, bytes.NewReader(save.Bytes()))
+	assert.ErrorIs(t, err, wecs.ErrIncompatibleParts)
+}
+
+func TestSerialWorldIncompatibleNested(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Vector := wecs.NewComponent[struct{ V [2]float32 }](registry, "vector")
+	world.New(Vector.With(struct{ V [2]float32 }{[2]float32{1.5, 2}}))
+
+	save := bytes.Buffer{}
+	assert.NoError(t, wecs.SerializeWorld(world, &save))
+
+	// the elements of a nested array changed kind without a migration
+	changedRegistry := wecs.NewRegistry()
+	wecs.NewComponent[struct{ V [2]int32 }](changedRegistry, "vector")
+
+	_, err := wecs.DeserializeWorld(changedRegistry, bytes.NewReader(save.Bytes()))
+	assert.ErrorIs(t, err, wecs.ErrIncompatibleParts)
+
+	// a migration converts the values instead
+	migratedRegistry := wecs.NewRegistry()
+	Migrated := wecs.NewComponent[struct{ V [2]int32 }](migratedRegistry, "vector")
+	wecs.AddMigration(migratedRegistry, Migrated, 0, func(old *struct{ V [2]float32 }, data *struct{ V [2]int32 }) {
+		data.V = [2]int32{int32(old.V[0] * 2), int32(old.V[1] * 2)}
+	})
+
+	loaded, err := wecs.DeserializeWorld(migratedRegistry, bytes.NewReader(save.Bytes()))
+	if assert.NoError(t, err) {
+		values := []struct{ V [2]int32 }{}
+		for value := range Migrated.Query(loaded, wecs.NewFilter()) {
+			values = append(values, *value)
+		}
+		assert.Equal(t, []struct{ V [2]int32 }{{[2]int32{3, 4}}}, values)
+	}
+}
+
+func TestSerialWorldMigration(t *testing.T) {
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Vector := wecs.NewComponent[vector](registry, "vector")
+
+	entity := world.New(Vector)
+	*Vector.Get(world, entity) = vector{3, -4}
+
+	save := bytes.Buffer{}
+	assert.NoError(t, wecs.SerializeWorld(world, &save))
+
+	// fields added and reordered are copied by name
+	type vector3 struct {
+		Z float32
+		Y float32
+		X float32
+	}
+
+	copyRegistry := wecs.NewRegistry()
+	CopyVector := wecs.NewComponent[vector3](copyRegistry, "vector")
+
+	loaded, err := wecs.DeserializeWorld(copyRegistry, bytes.NewReader(save.Bytes()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, vector3{0, -4, 3}, *CopyVector.Get(loaded, entity))
+	}
+
+	// fields that changed kind need a migration from the old version
+	type polar struct {
+		Length int32
+		Flip   bool
+	}
+
+	migrateRegistry := wecs.NewRegistry()
+	Polar := wecs.NewComponent[polar](migrateRegistry, "vector")
+	wecs.AddMigration(migrateRegistry, Polar, 0, func(old *vector, data *polar) {
+		data.Length = int32(old.X*old.X + old.Y*old.Y)
+		data.Flip = old.X < old.Y
+	})
+
+	loaded, err = wecs.DeserializeWorld(migrateRegistry, bytes.NewReader(save.Bytes()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, polar{25, false}, *Polar.Get(loaded, entity))
+	}
+
+	// saves of the new version skip the migration
+	save.Reset()
+	assert.NoError(t, wecs.SerializeWorld(loaded, &save))
+
+	loaded, err = wecs.DeserializeWorld(migrateRegistry, bytes.NewReader(save.Bytes()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, polar{25, false}, *Polar.Get(loaded, entity))
+	}
+}
+
+func TestSerialWorldUnexported(t *testing.T) {
+	type hidden struct {
+		name string
+	}
+	type nested struct {
+		Name  string
+		Items []struct{ count int }
+	}
+	type plain struct {
+		x, y float32
+	}
+
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Hidden := wecs.NewComponent[hidden](registry, "hidden")
+	Nested := wecs.NewComponent[nested](registry, "nested")
+	Plain := wecs.NewComponent[plain](registry, "plain")
+
+	// plain data is saved by memory layout, so unexported fields are kept
+	world.New(Plain.With(plain{1, 2}))
+	save := bytes.Buffer{}
+	assert.NoError(t, wecs.SerializeWorld(world, &save))
+
+	// data with pointers would lose unexported fields
+	world.New(Hidden.With(hidden{"crate"}))
+	err := wecs.SerializeWorld(world, &bytes.Buffer{})
+	assert.ErrorIs(t, err, wecs.ErrUnsaveableParts)
+	assert.ErrorContains(t, err, `"hidden": unexported field name`)
+
+	other := wecs.NewWorld(registry)
+	other.New(Nested)
+	err = wecs.SerializeWorld(other, &bytes.Buffer{})
+	assert.ErrorIs(t, err, wecs.ErrUnsaveableParts)
+	assert.ErrorContains(t, err, `"nested": unexported field Items.count`)
+}
+
+func TestSerialWorldPointers(t *testing.T) {
+	type named struct {
+		Name string
+		Tags []string
+	}
+
+	registry := wecs.NewRegistry()
+	world := wecs.NewWorld(registry)
+	Named := wecs.NewComponent[named](registry, "named")
+
+	entity := world.New(Named)
+	*Named.Get(world, entity) = named{Name: "crate", Tags: []string{"wood", "heavy"}}
+
+	save := bytes.Buffer{}
+	assert.NoError(t, wecs.SerializeWorld(world, &save))
+
+	loaded, err := wecs.DeserializeWorld(registry, bytes.NewReader(save.Bytes()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, named{Name: "crate", Tags: []string{"wood", "heavy"}}, *Named.Get(loaded, entity))
+	}
+
+	// fields of parts with pointers are matched by name
+	type renamed struct {
+		Tags   []string
+		Weight float32
+	}
+
+	renamedRegistry := wecs.NewRegistry()
+	Renamed := wecs.NewComponent[renamed](renamedRegistry, "named")
+
+	loaded, err = wecs.DeserializeWorld(renamedRegistry, bytes.NewReader(save.Bytes()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, renamed{Tags: []string{"wood", "heavy"}}, *Renamed.Get(loaded, entity))
+	}
+
+	// fields that changed kind can't be decoded
+	type changed struct {
+		Name int
+	}
+
+	changedRegistry := wecs.NewRegistry()
+	wecs.NewComponent[changed](changedRegistry, "named")
+
+	_, err = wecs.DeserializeWorld(changedRegistry, bytes.NewReader(save.Bytes()))
+	assert.ErrorIs(t, err, wecs.ErrIncompatibleParts)
+	assert.ErrorContains(t, err, `"named"`)
+
+	// components that became tags or changed kind can't be decoded either
+	tagRegistry := wecs.NewRegistry()
+	wecs.NewTag(tagRegistry, "named")
+
+	_, err = wecs.DeserializeWorld(tagRegistry, bytes.NewReader(save.Bytes()))
+	assert.ErrorIs(t, err, wecs.ErrIncompatibleParts)
+	assert.ErrorContains(t, err, `"named"`)
+
+	stringRegistry := wecs.NewRegistry()
+	wecs.NewComponent[string](stringRegistry, "named")
+
+	_, err = wecs.DeserializeWorld(stringRegistry, bytes.NewReader(save.Bytes()))
+	assert.ErrorIs(t, err, wecs.ErrIncompatibleParts)
+	assert.ErrorContains(t, err, `"named"`)
+}
@@ -12,14 +12,24 @@ type System interface {
 	SetRuntime(runtime time.Duration)
 }
 
+// A system that can run at a tick given by a scheduler, holding it's commands in it's own buffer.
+// Other systems are run by schedulers through Run, with a buffer the scheduler holds.
+type bufferedSystem interface {
+	System
+	run(world *World, delta time.Duration, tick uint64) *Commands
+}
+
 // A system with state that finds entities and manipulates their components.
 type system[T any] struct {
 	state    *T
 	callback systemCallback[T]
 	runtime  time.Duration
+	commands *Commands
+	lastRun  uint64 // the tick the system last ran at, for change detection
 }
 
 // A function callback that runs a system.
+// Commands queued on the world are held in a buffer owned by the system.
 type systemCallback[T any] func(world *World, state *T, delta time.Duration, runtime time.Duration)
 
 func NewSystem[T any](state T, callback systemCallback[T]) System {
@@ -27,14 +37,23 @@ func NewSystem[T any](state T, callback systemCallback[T]) System {
 		state:    &state,
 		callback: callback,
 		runtime:  time.Duration(0),
+		commands: NewCommands(),
 	}
 }
 
-// Run a system using it's state.
+// Run a system using it's state, then apply the commands it queued followed by the commands queued on the world.
 func (system *system[T]) Run(world *World, delta time.Duration) {
-	system.callback(world, system.state, delta, system.runtime)
-	world.EmptyDeleteQueue()
+	system.run(world, delta, world.store.AdvanceTick()).Flush(world)
+	world.commands.Flush(world)
+}
+
+// Run a system using it's state at a tick, returning the commands it queued without applying them.
+func (system *system[T]) run(world *World, delta time.Duration, tick uint64) *Commands {
+	system.callback(world.view(system.commands, tick, system.lastRun), system.state, delta, system.runtime)
 	system.runtime += delta
+	system.lastRun = tick
+
+	return system.commands
 }
 
 func (system *system[T]) State() any {
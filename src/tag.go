@@ -1,30 +1,27 @@
 package main
 
 import (
+	"iter"
+
 	"github.com/averagestardust/wecs/internal/storage"
 )
 
-// An integer uniquely identifying a tag type.
+// An integer uniquely identifying a tag type within a registry.
 // Tags should identify boolean properties entity.
 // Should be created in a static order during world initialization.
 type Tag storage.PartId
 
-// The next tag id to be assigned when a component is created.
-// Starts from the maximum uint32 value and decrements.
-var nextTag uint32 = ^uint32(0)
-
-// Creates a new type of tag to identify boolean properties entities.
+// Creates a new type of tag to identify boolean properties entities on a registry.
+// The name must be unique within the registry and stay the same between versions, as saves match tags by name.
 // Should be used in a static order during world initialization.
-func NewTag() Tag {
-	tag := Tag(nextTag)
-	nextTag--
-
-	return tag
+func NewTag(registry *Registry, name string) Tag {
+	return Tag(registry.parts.NewTag(name))
 }
 
 // Remove a tag from an entity.
 func (tag Tag) Delete(world *World, entity Entity) (success bool) {
-	return world.store.DeletePart(storage.EntityId(entity), tag)
+	world.beforeRemove(entity, []storage.Part{tag})
+	return world.store.DeletePart(storage.EntityId(entity), tag, world.changeTick())
 }
 
 // Check if an entity has a tag.
@@ -34,7 +31,34 @@ func (tag Tag) Has(world *World, entity Entity) (has bool) {
 
 // Add a tag to an entity.
 func (tag Tag) Add(world *World, entity Entity) (success bool) {
-	return world.store.AddPart(storage.EntityId(entity), tag)
+	before, _ := world.store.PartsOf(storage.EntityId(entity))
+	if !world.store.AddPart(storage.EntityId(entity), tag, world.changeTick()) {
+		return false
+	}
+
+	world.afterAdd(entity, before, nil)
+	return true
+}
+
+// Return an iterator of entities the tag was removed from, including deleted entities, since the running system last ran.
+// Removals are recorded once the tag is registered with RecordRemoved, or otherwise from the first time this is used on a world,
+// and kept until every system has seen them.
+func (tag Tag) Removed(world *World) iter.Seq[Entity] {
+	return func(yield func(Entity) bool) {
+		entities, ticks, _ := world.store.Removals(storage.PartId(tag))
+		for i, entity := range entities {
+			if ticks[i] > world.lastRun && !yield(Entity(entity)) {
+				return
+			}
+		}
+	}
+}
+
+// Record the entities the tag is removed from in every world using the registry, from the moment the world is created.
+// Without this removals are only recorded once Removed is first used, missing those made before it.
+// Should be used during world initialization.
+func (tag Tag) RecordRemoved(registry *Registry) {
+	registry.parts.RecordRemovals(storage.PartId(tag))
 }
 
 // Get the part id of a tag.
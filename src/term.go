@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A component in a query with several components, either a Component which is required or an optional component.
+type Term[Data any] interface {
+	// get a typed view of the data on a page, failing if the page doesn't match the term
+	column(page *storage.Page) (values []Data, matches bool)
+	changeTicks(page *storage.Page) (ticks []storage.ChangeTicks)
+}
+
+// A component in a query that yields nil data for entities without it.
+type OptionalComponent[Data any] Component[Data]
+
+// Make a component optional in a query, so entities without it are still found.
+func Optional[Data any](component Component[Data]) OptionalComponent[Data] {
+	return OptionalComponent[Data](component)
+}
+
+func (component Component[Data]) column(page *storage.Page) (values []Data, matches bool) {
+	return column[Data](page, storage.PartId(component))
+}
+
+func (component Component[Data]) changeTicks(page *storage.Page) (ticks []storage.ChangeTicks) {
+	ticks, _ = page.ChangeTicks(storage.PartId(component))
+	return ticks
+}
+
+func (component OptionalComponent[Data]) column(page *storage.Page) (values []Data, matches bool) {
+	values, _ = column[Data](page, storage.PartId(component))
+	return values, true
+}
+
+func (component OptionalComponent[Data]) changeTicks(page *storage.Page) (ticks []storage.ChangeTicks) {
+	ticks, _ = page.ChangeTicks(storage.PartId(component))
+	return ticks
+}
+
+// A term that has change ticks, to mark the components of a query as changed.
+type tickedTerm interface {
+	changeTicks(page *storage.Page) (ticks []storage.ChangeTicks)
+}
+
+// Get the change ticks of several terms on a page, nil for optional terms the page doesn't have.
+func changeTicks(page *storage.Page, terms ...tickedTerm) (ticks [][]storage.ChangeTicks) {
+	ticks = make([][]storage.ChangeTicks, len(terms))
+	for i, term := range terms {
+		ticks[i] = term.changeTicks(page)
+	}
+
+	return ticks
+}
+
+// Mark a row as changed at a tick in several columns of change ticks.
+func markChanged(ticks [][]storage.ChangeTicks, index int, tick uint64) {
+	for _, column := range ticks {
+		if column != nil {
+			column[index].Changed = tick
+		}
+	}
+}
+
+// Get a pointer to a value in a column, or nil if the page didn't have the column.
+func at[Data any](values []Data, index int) *Data {
+	if values == nil {
+		return nil
+	}
+
+	return &values[index]
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"iter"
+
+	"github.com/averagestardust/wecs/internal/storage"
+)
+
+// A set of three components often used together.
+type Query3[A, B, C any] struct {
+	a Term[A]
+	b Term[B]
+	c Term[C]
+}
+
+// A row of data from three components of one entity, pointing into the world.
+type Row3[A, B, C any] struct {
+	A *A
+	B *B
+	C *C
+}
+
+// Create a set of three components to query for data together.
+// Components made optional with Optional yield nil for entities without them.
+func NewQuery3[A, B, C any](a Term[A], b Term[B], c Term[C]) Query3[A, B, C] {
+	return Query3[A, B, C]{a, b, c}
+}
+
+// Return an iterator of data from three component types from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query3[A, B, C]) Query(world *World, filter Matcher) iter.Seq[Row3[A, B, C]] {
+	return func(yield func(Row3[A, B, C]) bool) {
+		for _, row := range query.each(world, filter, false) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of data from three component types from all entities that match a filter, marking each as changed.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept.
+func (query Query3[A, B, C]) QueryMut(world *World, filter Matcher) iter.Seq[Row3[A, B, C]] {
+	return func(yield func(Row3[A, B, C]) bool) {
+		for _, row := range query.each(world, filter, true) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from three component types, from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query3[A, B, C]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, Row3[A, B, C]] {
+	return query.each(world, filter, false)
+}
+
+// iterate the rows of matching entities, marking their components as changed if mark is set
+func (query Query3[A, B, C]) each(world *World, filter Matcher, mark bool) iter.Seq2[Entity, Row3[A, B, C]] {
+	return func(yield func(Entity, Row3[A, B, C]) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			aValues, aMatches := query.a.column(page)
+			bValues, bMatches := query.b.column(page)
+			cValues, cMatches := query.c.column(page)
+			if !aMatches || !bMatches || !cMatches {
+				continue
+			}
+
+			var ticks [][]storage.ChangeTicks
+			if mark {
+				ticks = changeTicks(page, query.a, query.b, query.c)
+			}
+
+			for i := range page.Size {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				markChanged(ticks, i, tick)
+				if !yield(Entity(page.Entities[i]), Row3[A, B, C]{at(aValues, i), at(bValues, i), at(cValues, i)}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// A set of four components often used together.
+type Query4[A, B, C, D any] struct {
+	a Term[A]
+	b Term[B]
+	c Term[C]
+	d Term[D]
+}
+
+// A row of data from four components of one entity, pointing into the world.
+type Row4[A, B, C, D any] struct {
+	A *A
+	B *B
+	C *C
+	D *D
+}
+
+// Create a set of four components to query for data together.
+// Components made optional with Optional yield nil for entities without them.
+func NewQuery4[A, B, C, D any](a Term[A], b Term[B], c Term[C], d Term[D]) Query4[A, B, C, D] {
+	return Query4[A, B, C, D]{a, b, c, d}
+}
+
+// Return an iterator of data from four component types from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query4[A, B, C, D]) Query(world *World, filter Matcher) iter.Seq[Row4[A, B, C, D]] {
+	return func(yield func(Row4[A, B, C, D]) bool) {
+		for _, row := range query.each(world, filter, false) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of data from four component types from all entities that match a filter, marking each as changed.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept.
+func (query Query4[A, B, C, D]) QueryMut(world *World, filter Matcher) iter.Seq[Row4[A, B, C, D]] {
+	return func(yield func(Row4[A, B, C, D]) bool) {
+		for _, row := range query.each(world, filter, true) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from four component types, from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query4[A, B, C, D]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, Row4[A, B, C, D]] {
+	return query.each(world, filter, false)
+}
+
+// iterate the rows of matching entities, marking their components as changed if mark is set
+func (query Query4[A, B, C, D]) each(world *World, filter Matcher, mark bool) iter.Seq2[Entity, Row4[A, B, C, D]] {
+	return func(yield func(Entity, Row4[A, B, C, D]) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			aValues, aMatches := query.a.column(page)
+			bValues, bMatches := query.b.column(page)
+			cValues, cMatches := query.c.column(page)
+			dValues, dMatches := query.d.column(page)
+			if !aMatches || !bMatches || !cMatches || !dMatches {
+				continue
+			}
+
+			var ticks [][]storage.ChangeTicks
+			if mark {
+				ticks = changeTicks(page, query.a, query.b, query.c, query.d)
+			}
+
+			for i := range page.Size {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				markChanged(ticks, i, tick)
+				if !yield(Entity(page.Entities[i]), Row4[A, B, C, D]{at(aValues, i), at(bValues, i), at(cValues, i), at(dValues, i)}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// A set of five components often used together.
+type Query5[A, B, C, D, E any] struct {
+	a Term[A]
+	b Term[B]
+	c Term[C]
+	d Term[D]
+	e Term[E]
+}
+
+// A row of data from five components of one entity, pointing into the world.
+type Row5[A, B, C, D, E any] struct {
+	A *A
+	B *B
+	C *C
+	D *D
+	E *E
+}
+
+// Create a set of five components to query for data together.
+// Components made optional with Optional yield nil for entities without them.
+func NewQuery5[A, B, C, D, E any](a Term[A], b Term[B], c Term[C], d Term[D], e Term[E]) Query5[A, B, C, D, E] {
+	return Query5[A, B, C, D, E]{a, b, c, d, e}
+}
+
+// Return an iterator of data from five component types from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query5[A, B, C, D, E]) Query(world *World, filter Matcher) iter.Seq[Row5[A, B, C, D, E]] {
+	return func(yield func(Row5[A, B, C, D, E]) bool) {
+		for _, row := range query.each(world, filter, false) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of data from five component types from all entities that match a filter, marking each as changed.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept.
+func (query Query5[A, B, C, D, E]) QueryMut(world *World, filter Matcher) iter.Seq[Row5[A, B, C, D, E]] {
+	return func(yield func(Row5[A, B, C, D, E]) bool) {
+		for _, row := range query.each(world, filter, true) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from five component types, from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query5[A, B, C, D, E]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, Row5[A, B, C, D, E]] {
+	return query.each(world, filter, false)
+}
+
+// iterate the rows of matching entities, marking their components as changed if mark is set
+func (query Query5[A, B, C, D, E]) each(world *World, filter Matcher, mark bool) iter.Seq2[Entity, Row5[A, B, C, D, E]] {
+	return func(yield func(Entity, Row5[A, B, C, D, E]) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			aValues, aMatches := query.a.column(page)
+			bValues, bMatches := query.b.column(page)
+			cValues, cMatches := query.c.column(page)
+			dValues, dMatches := query.d.column(page)
+			eValues, eMatches := query.e.column(page)
+			if !aMatches || !bMatches || !cMatches || !dMatches || !eMatches {
+				continue
+			}
+
+			var ticks [][]storage.ChangeTicks
+			if mark {
+				ticks = changeTicks(page, query.a, query.b, query.c, query.d, query.e)
+			}
+
+			for i := range page.Size {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				markChanged(ticks, i, tick)
+				if !yield(Entity(page.Entities[i]), Row5[A, B, C, D, E]{at(aValues, i), at(bValues, i), at(cValues, i), at(dValues, i), at(eValues, i)}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// A set of six components often used together.
+type Query6[A, B, C, D, E, F any] struct {
+	a Term[A]
+	b Term[B]
+	c Term[C]
+	d Term[D]
+	e Term[E]
+	f Term[F]
+}
+
+// A row of data from six components of one entity, pointing into the world.
+type Row6[A, B, C, D, E, F any] struct {
+	A *A
+	B *B
+	C *C
+	D *D
+	E *E
+	F *F
+}
+
+// Create a set of six components to query for data together.
+// Components made optional with Optional yield nil for entities without them.
+func NewQuery6[A, B, C, D, E, F any](a Term[A], b Term[B], c Term[C], d Term[D], e Term[E], f Term[F]) Query6[A, B, C, D, E, F] {
+	return Query6[A, B, C, D, E, F]{a, b, c, d, e, f}
+}
+
+// Return an iterator of data from six component types from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query6[A, B, C, D, E, F]) Query(world *World, filter Matcher) iter.Seq[Row6[A, B, C, D, E, F]] {
+	return func(yield func(Row6[A, B, C, D, E, F]) bool) {
+		for _, row := range query.each(world, filter, false) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of data from six component types from all entities that match a filter, marking each as changed.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept.
+func (query Query6[A, B, C, D, E, F]) QueryMut(world *World, filter Matcher) iter.Seq[Row6[A, B, C, D, E, F]] {
+	return func(yield func(Row6[A, B, C, D, E, F]) bool) {
+		for _, row := range query.each(world, filter, true) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from six component types, from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query6[A, B, C, D, E, F]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, Row6[A, B, C, D, E, F]] {
+	return query.each(world, filter, false)
+}
+
+// iterate the rows of matching entities, marking their components as changed if mark is set
+func (query Query6[A, B, C, D, E, F]) each(world *World, filter Matcher, mark bool) iter.Seq2[Entity, Row6[A, B, C, D, E, F]] {
+	return func(yield func(Entity, Row6[A, B, C, D, E, F]) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			aValues, aMatches := query.a.column(page)
+			bValues, bMatches := query.b.column(page)
+			cValues, cMatches := query.c.column(page)
+			dValues, dMatches := query.d.column(page)
+			eValues, eMatches := query.e.column(page)
+			fValues, fMatches := query.f.column(page)
+			if !aMatches || !bMatches || !cMatches || !dMatches || !eMatches || !fMatches {
+				continue
+			}
+
+			var ticks [][]storage.ChangeTicks
+			if mark {
+				ticks = changeTicks(page, query.a, query.b, query.c, query.d, query.e, query.f)
+			}
+
+			for i := range page.Size {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				markChanged(ticks, i, tick)
+				if !yield(Entity(page.Entities[i]), Row6[A, B, C, D, E, F]{at(aValues, i), at(bValues, i), at(cValues, i), at(dValues, i), at(eValues, i), at(fValues, i)}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// A set of seven components often used together.
+type Query7[A, B, C, D, E, F, G any] struct {
+	a Term[A]
+	b Term[B]
+	c Term[C]
+	d Term[D]
+	e Term[E]
+	f Term[F]
+	g Term[G]
+}
+
+// A row of data from seven components of one entity, pointing into the world.
+type Row7[A, B, C, D, E, F, G any] struct {
+	A *A
+	B *B
+	C *C
+	D *D
+	E *E
+	F *F
+	G *G
+}
+
+// Create a set of seven components to query for data together.
+// Components made optional with Optional yield nil for entities without them.
+func NewQuery7[A, B, C, D, E, F, G any](a Term[A], b Term[B], c Term[C], d Term[D], e Term[E], f Term[F], g Term[G]) Query7[A, B, C, D, E, F, G] {
+	return Query7[A, B, C, D, E, F, G]{a, b, c, d, e, f, g}
+}
+
+// Return an iterator of data from seven component types from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query7[A, B, C, D, E, F, G]) Query(world *World, filter Matcher) iter.Seq[Row7[A, B, C, D, E, F, G]] {
+	return func(yield func(Row7[A, B, C, D, E, F, G]) bool) {
+		for _, row := range query.each(world, filter, false) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of data from seven component types from all entities that match a filter, marking each as changed.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept.
+func (query Query7[A, B, C, D, E, F, G]) QueryMut(world *World, filter Matcher) iter.Seq[Row7[A, B, C, D, E, F, G]] {
+	return func(yield func(Row7[A, B, C, D, E, F, G]) bool) {
+		for _, row := range query.each(world, filter, true) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from seven component types, from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query7[A, B, C, D, E, F, G]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, Row7[A, B, C, D, E, F, G]] {
+	return query.each(world, filter, false)
+}
+
+// iterate the rows of matching entities, marking their components as changed if mark is set
+func (query Query7[A, B, C, D, E, F, G]) each(world *World, filter Matcher, mark bool) iter.Seq2[Entity, Row7[A, B, C, D, E, F, G]] {
+	return func(yield func(Entity, Row7[A, B, C, D, E, F, G]) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			aValues, aMatches := query.a.column(page)
+			bValues, bMatches := query.b.column(page)
+			cValues, cMatches := query.c.column(page)
+			dValues, dMatches := query.d.column(page)
+			eValues, eMatches := query.e.column(page)
+			fValues, fMatches := query.f.column(page)
+			gValues, gMatches := query.g.column(page)
+			if !aMatches || !bMatches || !cMatches || !dMatches || !eMatches || !fMatches || !gMatches {
+				continue
+			}
+
+			var ticks [][]storage.ChangeTicks
+			if mark {
+				ticks = changeTicks(page, query.a, query.b, query.c, query.d, query.e, query.f, query.g)
+			}
+
+			for i := range page.Size {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				markChanged(ticks, i, tick)
+				if !yield(Entity(page.Entities[i]), Row7[A, B, C, D, E, F, G]{at(aValues, i), at(bValues, i), at(cValues, i), at(dValues, i), at(eValues, i), at(fValues, i), at(gValues, i)}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// A set of eight components often used together.
+type Query8[A, B, C, D, E, F, G, H any] struct {
+	a Term[A]
+	b Term[B]
+	c Term[C]
+	d Term[D]
+	e Term[E]
+	f Term[F]
+	g Term[G]
+	h Term[H]
+}
+
+// A row of data from eight components of one entity, pointing into the world.
+type Row8[A, B, C, D, E, F, G, H any] struct {
+	A *A
+	B *B
+	C *C
+	D *D
+	E *E
+	F *F
+	G *G
+	H *H
+}
+
+// Create a set of eight components to query for data together.
+// Components made optional with Optional yield nil for entities without them.
+func NewQuery8[A, B, C, D, E, F, G, H any](a Term[A], b Term[B], c Term[C], d Term[D], e Term[E], f Term[F], g Term[G], h Term[H]) Query8[A, B, C, D, E, F, G, H] {
+	return Query8[A, B, C, D, E, F, G, H]{a, b, c, d, e, f, g, h}
+}
+
+// Return an iterator of data from eight component types from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query8[A, B, C, D, E, F, G, H]) Query(world *World, filter Matcher) iter.Seq[Row8[A, B, C, D, E, F, G, H]] {
+	return func(yield func(Row8[A, B, C, D, E, F, G, H]) bool) {
+		for _, row := range query.each(world, filter, false) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of data from eight component types from all entities that match a filter, marking each as changed.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept.
+func (query Query8[A, B, C, D, E, F, G, H]) QueryMut(world *World, filter Matcher) iter.Seq[Row8[A, B, C, D, E, F, G, H]] {
+	return func(yield func(Row8[A, B, C, D, E, F, G, H]) bool) {
+		for _, row := range query.each(world, filter, true) {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// Return an iterator of entities and their data from eight component types, from all entities that match a filter.
+// Entities without every component are skipped, unless the component is optional.
+// The data points into the world, so writes through it are kept, but aren't seen by Filter.Changed.
+func (query Query8[A, B, C, D, E, F, G, H]) QueryWithEntity(world *World, filter Matcher) iter.Seq2[Entity, Row8[A, B, C, D, E, F, G, H]] {
+	return query.each(world, filter, false)
+}
+
+// iterate the rows of matching entities, marking their components as changed if mark is set
+func (query Query8[A, B, C, D, E, F, G, H]) each(world *World, filter Matcher, mark bool) iter.Seq2[Entity, Row8[A, B, C, D, E, F, G, H]] {
+	return func(yield func(Entity, Row8[A, B, C, D, E, F, G, H]) bool) {
+		tick := world.changeTick()
+
+		for page, rows := range filter.filter(world) {
+			aValues, aMatches := query.a.column(page)
+			bValues, bMatches := query.b.column(page)
+			cValues, cMatches := query.c.column(page)
+			dValues, dMatches := query.d.column(page)
+			eValues, eMatches := query.e.column(page)
+			fValues, fMatches := query.f.column(page)
+			gValues, gMatches := query.g.column(page)
+			hValues, hMatches := query.h.column(page)
+			if !aMatches || !bMatches || !cMatches || !dMatches || !eMatches || !fMatches || !gMatches || !hMatches {
+				continue
+			}
+
+			var ticks [][]storage.ChangeTicks
+			if mark {
+				ticks = changeTicks(page, query.a, query.b, query.c, query.d, query.e, query.f, query.g, query.h)
+			}
+
+			for i := range page.Size {
+				if rows != nil && !rows(i) {
+					continue
+				}
+
+				markChanged(ticks, i, tick)
+				if !yield(Entity(page.Entities[i]), Row8[A, B, C, D, E, F, G, H]{at(aValues, i), at(bValues, i), at(cValues, i), at(dValues, i), at(eValues, i), at(fValues, i), at(gValues, i), at(hValues, i)}) {
+					return
+				}
+			}
+		}
+	}
+}
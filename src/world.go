@@ -1,37 +1,90 @@
 package main
 
 import (
+	"iter"
+
 	"github.com/averagestardust/wecs/internal/storage"
 )
 
 type World struct {
-	store       *storage.Store
-	deleteQueue map[Entity]struct{}
+	registry *Registry
+	store    *storage.Store
+	commands *Commands
+	hooks    *hooks
+	thisRun  uint64 // the tick of the running system, or zero outside of systems
+	lastRun  uint64 // the tick the running system last ran at, or zero outside of systems
 }
 
-// Create a new world.
-func NewWorld() *World {
+// Create a new world using the components and tags of a registry.
+func NewWorld(registry *Registry) *World {
 	return &World{
-		store:       storage.NewStore(),
-		deleteQueue: map[Entity]struct{}{},
+		registry: registry,
+		store:    storage.NewStore(registry.parts),
+		commands: NewCommands(),
+		hooks:    newHooks(),
 	}
 }
 
+// Get the registry of components and tags a world uses.
+func (world *World) Registry() *Registry {
+	return world.registry
+}
+
+// Get the buffer of commands queued on a world.
+// Systems see their own buffer, which is applied after they run.
+func (world *World) Commands() *Commands {
+	return world.commands
+}
+
 // Check if an entity is exists and hasn't been queued for deletion.
 func (world *World) Alive(entity Entity) bool {
-	_, deleteIsQueued := world.deleteQueue[entity]
-	return !deleteIsQueued && world.Exists(entity)
+	return !world.commands.Deleted(entity) && world.Exists(entity)
 }
 
 // Queue an entity for deletion after the access is closed.
 func (world *World) QueueDelete(entity Entity) {
-	world.deleteQueue[entity] = struct{}{}
+	world.commands.Delete(entity)
 }
 
-// Immediately delete all entities that have been queued for deletions.
+// Immediately apply all queued commands, including deleting entities that have been queued for deletion.
 func (world *World) EmptyDeleteQueue() {
-	for entity := range world.deleteQueue {
-		world.store.Delete(storage.EntityId(entity))
-		delete(world.deleteQueue, entity)
+	world.commands.Flush(world)
+}
+
+// Return an iterator of entities deleted since the running system last ran.
+// Deletions are recorded once the registry is set up with RecordDeleted, or otherwise from the first time this is used on a world,
+// and kept until every system has seen them.
+func (world *World) Deleted() iter.Seq[Entity] {
+	return func(yield func(Entity) bool) {
+		entities, ticks := world.store.Deletions()
+		for i, entity := range entities {
+			if ticks[i] > world.lastRun && !yield(Entity(entity)) {
+				return
+			}
+		}
 	}
 }
+
+// Forget every recorded removal and deletion.
+// Schedulers do this as systems see them, but worlds with systems run by hand should do this after all of them run.
+func (world *World) ClearRemoved() {
+	world.store.PruneRemovals(^uint64(0))
+}
+
+// Get a view of the world for a system that queues commands into it's own buffer, and marks changes with the tick of it's run.
+func (world *World) view(commands *Commands, thisRun uint64, lastRun uint64) *World {
+	view := *world
+	view.commands = commands
+	view.thisRun = thisRun
+	view.lastRun = lastRun
+	return &view
+}
+
+// Get the tick to mark changes with, which is the tick of the running system.
+func (world *World) changeTick() uint64 {
+	if world.thisRun != 0 {
+		return world.thisRun
+	}
+
+	return world.store.Tick
+}